@@ -41,13 +41,23 @@ func (a *AstPrinter) VisitExpressionStmt(stmt *Expression) any {
 
 func (a *AstPrinter) VisitFunctionStmt(stmt *Function) any {
 	var builder strings.Builder
-	builder.WriteString(fmt.Sprintf("(%s (", stmt.Name.Lexeme))
+	builder.WriteString(fmt.Sprintf("(%s (", functionName(stmt)))
 
-	for _, param := range stmt.Params {
-		if param != stmt.Params[0] {
+	for k, param := range stmt.Params {
+		if k > 0 {
 			builder.WriteRune(' ')
 		}
-		builder.WriteString(param.Lexeme)
+		if stmt.Defaults[k] != nil {
+			builder.WriteString(a.parenthesizeAny("=", param.Lexeme, stmt.Defaults[k]))
+		} else {
+			builder.WriteString(param.Lexeme)
+		}
+	}
+	if stmt.Rest != nil {
+		if len(stmt.Params) > 0 {
+			builder.WriteRune(' ')
+		}
+		builder.WriteString("..." + stmt.Rest.Lexeme)
 	}
 
 	builder.WriteString(") ")
@@ -64,6 +74,22 @@ func (a *AstPrinter) VisitIfStmt(stmt *If) any {
 	return a.parenthesizeAny("if", stmt.Condition, stmt.ThenBranch, "else", stmt.ElseBranch)
 }
 
+func (a *AstPrinter) VisitMatchStmt(stmt *Match) any {
+	vals := []any{stmt.Value}
+	for k, patterns := range stmt.Patterns {
+		arm := []any{patterns}
+		if stmt.Guards[k] != nil {
+			arm = append(arm, "if", stmt.Guards[k])
+		}
+		arm = append(arm, stmt.Bodies[k])
+		vals = append(vals, a.parenthesizeAny("case", arm...))
+	}
+	if stmt.Otherwise != nil {
+		vals = append(vals, a.parenthesizeAny("default", stmt.Otherwise))
+	}
+	return a.parenthesizeAny("match", vals...)
+}
+
 func (a *AstPrinter) VisitPrintStmt(stmt *Print) any {
 	return a.parenthesize("print", stmt.Expression)
 }
@@ -72,14 +98,63 @@ func (a *AstPrinter) VisitReturnStmt(stmt *Return) any {
 	return a.parenthesize("return", stmt.Value)
 }
 
+func (a *AstPrinter) VisitYieldStmt(stmt *Yield) any {
+	return a.parenthesize("yield", stmt.Value)
+}
+
+func (a *AstPrinter) VisitThrowStmt(stmt *Throw) any {
+	return a.parenthesize("throw", stmt.Value)
+}
+
+func (a *AstPrinter) VisitTryStmt(stmt *Try) any {
+	vals := []any{NewBlock(stmt.Body)}
+	if stmt.Name != nil {
+		vals = append(vals, "catch", stmt.Name, NewBlock(stmt.Handler))
+	}
+	if stmt.Finally != nil {
+		vals = append(vals, "finally", NewBlock(stmt.Finally))
+	}
+	return a.parenthesizeAny("try", vals...)
+}
+
 func (a *AstPrinter) VisitVarStmt(stmt *Var) any {
 	return a.parenthesizeAny("var", stmt.Name.Lexeme, stmt.Initializer) + "\n"
 }
 
 func (a *AstPrinter) VisitWhileStmt(stmt *While) any {
+	if stmt.Increment != nil {
+		return a.parenthesizeAny("while", stmt.Condition, stmt.Body, stmt.Increment)
+	}
 	return a.parenthesizeAny("while", stmt.Condition, stmt.Body)
 }
 
+func (a *AstPrinter) VisitForInStmt(stmt *ForIn) any {
+	return a.parenthesizeAny("for-in", stmt.Name, stmt.Iterable, stmt.Body)
+}
+
+func (a *AstPrinter) VisitExportStmt(stmt *Export) any {
+	return a.parenthesizeAny("export", stmt.Declaration)
+}
+
+func (a *AstPrinter) VisitImportStmt(stmt *Import) any {
+	if stmt.Alias != nil {
+		return a.parenthesizeAny("import", stmt.Path.Lexeme, stmt.Alias)
+	}
+	names := make([]any, len(stmt.Names))
+	for k, name := range stmt.Names {
+		names[k] = name
+	}
+	return a.parenthesizeAny("import", append([]any{stmt.Path.Lexeme}, names...)...)
+}
+
+func (a *AstPrinter) VisitBreakStmt(stmt *Break) any {
+	return "(break)"
+}
+
+func (a *AstPrinter) VisitContinueStmt(stmt *Continue) any {
+	return "(continue)"
+}
+
 func (a *AstPrinter) VisitAssignExpr(expr *Assign) any {
 	return a.parenthesizeAny("assign", expr.Name.Lexeme, expr.Value)
 }
@@ -92,6 +167,18 @@ func (a *AstPrinter) VisitCallExpr(expr *Call) any {
 	return a.parenthesizeAny("call", expr.Callee, expr.Arguments)
 }
 
+func (a *AstPrinter) VisitCompoundExpr(expr *Compound) any {
+	name := expr.Operator.Lexeme
+	if expr.Postfix {
+		return a.parenthesize("postfix"+name, expr.Target)
+	}
+	return a.parenthesize(name, expr.Target, expr.Value)
+}
+
+func (a *AstPrinter) VisitConditionalExpr(expr *Conditional) any {
+	return a.parenthesize("?:", expr.Condition, expr.ThenBranch, expr.ElseBranch)
+}
+
 func (a *AstPrinter) VisitGetExpr(expr *Get) any {
 	return a.parenthesizeAny("get", expr.Object, expr.Name.Lexeme)
 }
@@ -100,6 +187,18 @@ func (a *AstPrinter) VisitGroupingExpr(expr *Grouping) any {
 	return a.parenthesize("group", expr.Expression)
 }
 
+func (a *AstPrinter) VisitIndexExpr(expr *Index) any {
+	return a.parenthesize("index", expr.Object, expr.Index)
+}
+
+func (a *AstPrinter) VisitLambdaExpr(expr *Lambda) any {
+	return expr.Function.Accept(a)
+}
+
+func (a *AstPrinter) VisitListExpr(expr *List) any {
+	return a.parenthesizeAny("list", expr.Elements)
+}
+
 func (a *AstPrinter) VisitLiteralExpr(expr *Literal) any {
 	if expr.Value == nil {
 		return "nil"
@@ -111,10 +210,22 @@ func (a *AstPrinter) VisitLogicalExpr(expr *Logical) any {
 	return a.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
 }
 
+func (a *AstPrinter) VisitMapExpr(expr *Map) any {
+	return a.parenthesizeAny("map", expr.Keys, expr.Values)
+}
+
 func (a *AstPrinter) VisitSetExpr(expr *Set) any {
 	return a.parenthesizeAny("set", expr.Object, expr.Name.Lexeme, expr.Value)
 }
 
+func (a *AstPrinter) VisitSetIndexExpr(expr *SetIndex) any {
+	return a.parenthesize("setindex", expr.Object, expr.Index, expr.Value)
+}
+
+func (a *AstPrinter) VisitStringifyExpr(expr *Stringify) any {
+	return a.parenthesize("str", expr.Expression)
+}
+
 func (a *AstPrinter) VisitSuperExpr(expr *Super) any {
 	return a.parenthesize("super", expr)
 }
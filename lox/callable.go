@@ -1,6 +1,15 @@
 package lox
 
 type Callable interface {
-	Arity() int
+	// Arity returns the least and the most arguments the callable accepts.
+	// max is Variadic if there is no upper bound.
+	Arity() (min, max int)
 	Call(interpreter *Interpreter, args []any) any
 }
+
+// KeywordCallable is a callable whose parameters can also be passed by
+// name. Parameters returns the names in positional order.
+type KeywordCallable interface {
+	Callable
+	Parameters() []string
+}
@@ -0,0 +1,113 @@
+package lox
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CallFrame is a single entry of a runtime error traceback. Line is the
+// line being executed in the frame when the error occurred.
+type CallFrame struct {
+	Function string
+	Class    string
+	Line     int
+}
+
+func (c CallFrame) String() string {
+	if c.Class != "" {
+		return fmt.Sprintf("%s.%s (line %d)", c.Class, c.Function, c.Line)
+	}
+	return fmt.Sprintf("%s (line %d)", c.Function, c.Line)
+}
+
+// frame is an active call on the interpreter's call stack.
+type frame struct {
+	function string
+	class    string
+	paren    *Token
+}
+
+func newFrame(callee Callable, paren *Token) frame {
+	switch callee := callee.(type) {
+	case *LoxFunction:
+		var class string
+		if callee.Class != nil {
+			class = callee.Class.Name
+		}
+		return frame{functionName(callee.Declaration), class, paren}
+	case *Closure:
+		var class string
+		if callee.Class != nil {
+			class = callee.Class.Name
+		}
+		return frame{callee.Function.Name, class, paren}
+	case *BoundMethod:
+		return newFrame(callee.Method, paren)
+	case *LoxClass:
+		return frame{"init", callee.Name, paren}
+	case *NativeFunc:
+		return frame{callee.Name, "", paren}
+	default:
+		return frame{fmt.Sprint(callee), "", paren}
+	}
+}
+
+func (i *Interpreter) pushFrame(callee Callable, paren *Token) {
+	i.frames = append(i.frames, newFrame(callee, paren))
+}
+
+func (i *Interpreter) popFrame() {
+	i.frames = i.frames[:len(i.frames)-1]
+}
+
+// traceback captures the call stack, innermost frame first, for an error
+// raised at token.
+func (i *Interpreter) traceback(token *Token) []CallFrame {
+	frames := slices.Concat(i.callers, i.frames)
+	trace := make([]CallFrame, 0, len(frames)+1)
+	line := token.Line
+	for k := len(frames) - 1; k >= 0; k-- {
+		frame := frames[k]
+		trace = append(trace, CallFrame{frame.function, frame.class, line})
+		line = frame.paren.Line
+	}
+	return append(trace, CallFrame{"script", "", line})
+}
+
+// maxRepeatedFrames is how many identical consecutive frames a traceback
+// shows before summarizing the rest, which keeps deep recursion readable.
+const maxRepeatedFrames = 3
+
+func formatTraceback(frames []CallFrame) string {
+	var builder strings.Builder
+	repeated := 0
+
+	flush := func() {
+		if repeated > maxRepeatedFrames {
+			fmt.Fprintf(&builder, "\n  [previous frame repeated %d more times]",
+				repeated-maxRepeatedFrames)
+		}
+	}
+
+	for k, frame := range frames {
+		if k > 0 && frame == frames[k-1] {
+			repeated++
+			if repeated > maxRepeatedFrames {
+				continue
+			}
+		} else {
+			flush()
+			repeated = 1
+		}
+
+		if k == 0 {
+			builder.WriteString("  in ")
+		} else {
+			builder.WriteString("\n  called from ")
+		}
+		builder.WriteString(frame.String())
+	}
+	flush()
+	return builder.String()
+}
@@ -0,0 +1,62 @@
+package lox
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Capability names a group of native functions with access to the outside
+// world. Natives that require a capability can only be called by engines
+// that were granted it.
+type Capability string
+
+const (
+	CapFSRead  Capability = "fs.read"
+	CapFSWrite Capability = "fs.write"
+	CapEnv     Capability = "env"
+	CapExec    Capability = "exec"
+	CapTime    Capability = "time"
+)
+
+// Capabilities lists the capabilities used by the built-in natives.
+var Capabilities = []Capability{CapFSRead, CapFSWrite, CapEnv, CapExec, CapTime}
+
+// ParseCapability validates the name of a built-in capability.
+func ParseCapability(name string) (Capability, error) {
+	capability := Capability(name)
+	if !slices.Contains(Capabilities, capability) {
+		return "", fmt.Errorf("unknown capability '%s'", name)
+	}
+	return capability, nil
+}
+
+// WithCapabilities grants capabilities to the engine. Engines start with
+// none.
+func WithCapabilities(capabilities ...Capability) Option {
+	return func(l *Lox) {
+		for _, capability := range capabilities {
+			l.capabilities[capability] = true
+		}
+	}
+}
+
+// Allowed reports whether the engine was granted capability.
+func (l *Lox) Allowed(capability Capability) bool {
+	return capability == "" || l.capabilities[capability]
+}
+
+// DefineRestricted is like DefineNative, but calling the native fails
+// unless the engine was granted capability.
+func (i *Interpreter) DefineRestricted(name string, capability Capability, arity int, fn NativeFn) {
+	native := NewNativeFunc(name, arity, fn)
+	native.Capability = capability
+	i.builtins.Define(name, native)
+}
+
+func (i *Interpreter) checkCapability(native *NativeFunc) {
+	if !i.lox.Allowed(native.Capability) {
+		panic(NewRuntimeError(i.callSite(),
+			fmt.Sprintf("Native function '%s' requires the '%s' capability.",
+				native.Name, native.Capability)))
+	}
+}
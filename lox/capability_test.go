@@ -0,0 +1,27 @@
+package lox_test
+
+import (
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+func TestCapabilities(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out, lox.WithCapabilities(lox.CapTime))
+		if err := l.Run(`print clock() > 0;`); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != "true\n" {
+			t.Errorf("got output %q, want %q", got, "true\n")
+		}
+
+		err := l.Run(`getenv("HOME");`)
+		want := "Native function 'getenv' requires the 'env' capability."
+		if got := runtimeError(t, err); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
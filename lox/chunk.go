@@ -0,0 +1,243 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type OpCode byte
+
+const (
+	OP_CONSTANT OpCode = iota
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+	OP_DUP
+	OP_BURY
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	OP_GET_GLOBAL
+	OP_DEFINE_GLOBAL
+	OP_SET_GLOBAL
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_GET_SUPER
+	OP_GET_INDEX
+	OP_SET_INDEX
+	OP_LIST
+	OP_MAP
+	OP_EQUAL
+	OP_MATCH
+	OP_NOT_EQUAL
+	OP_GREATER
+	OP_GREATER_EQUAL
+	OP_LESS
+	OP_LESS_EQUAL
+	OP_ADD
+	OP_SUBTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_MODULO
+	OP_INT_DIVIDE
+	OP_POWER
+	OP_NOT
+	OP_NEGATE
+	OP_STRINGIFY
+	OP_PRINT
+	OP_JUMP
+	OP_JUMP_IF_FALSE
+	OP_JUMP_IF_GIVEN
+	OP_LOOP
+	OP_ITERATOR
+	OP_FOR_ITER
+	OP_CALL
+	OP_CALL_KEYWORDS
+	OP_CLOSURE
+	OP_CLOSE_UPVALUE
+	OP_RETURN
+	OP_YIELD
+	OP_THROW
+	OP_TRY
+	OP_POP_HANDLER
+	OP_CATCH
+	OP_RETHROW
+	OP_IMPORT
+	OP_CLASS
+	OP_INHERIT
+	OP_METHOD
+	OP_CLASS_METHOD
+)
+
+var opNames = [...]string{
+	OP_CONSTANT:      "OP_CONSTANT",
+	OP_NIL:           "OP_NIL",
+	OP_TRUE:          "OP_TRUE",
+	OP_FALSE:         "OP_FALSE",
+	OP_POP:           "OP_POP",
+	OP_DUP:           "OP_DUP",
+	OP_BURY:          "OP_BURY",
+	OP_GET_LOCAL:     "OP_GET_LOCAL",
+	OP_SET_LOCAL:     "OP_SET_LOCAL",
+	OP_GET_GLOBAL:    "OP_GET_GLOBAL",
+	OP_DEFINE_GLOBAL: "OP_DEFINE_GLOBAL",
+	OP_SET_GLOBAL:    "OP_SET_GLOBAL",
+	OP_GET_UPVALUE:   "OP_GET_UPVALUE",
+	OP_SET_UPVALUE:   "OP_SET_UPVALUE",
+	OP_GET_PROPERTY:  "OP_GET_PROPERTY",
+	OP_SET_PROPERTY:  "OP_SET_PROPERTY",
+	OP_GET_SUPER:     "OP_GET_SUPER",
+	OP_GET_INDEX:     "OP_GET_INDEX",
+	OP_SET_INDEX:     "OP_SET_INDEX",
+	OP_LIST:          "OP_LIST",
+	OP_MAP:           "OP_MAP",
+	OP_EQUAL:         "OP_EQUAL",
+	OP_MATCH:         "OP_MATCH",
+	OP_NOT_EQUAL:     "OP_NOT_EQUAL",
+	OP_GREATER:       "OP_GREATER",
+	OP_GREATER_EQUAL: "OP_GREATER_EQUAL",
+	OP_LESS:          "OP_LESS",
+	OP_LESS_EQUAL:    "OP_LESS_EQUAL",
+	OP_ADD:           "OP_ADD",
+	OP_SUBTRACT:      "OP_SUBTRACT",
+	OP_MULTIPLY:      "OP_MULTIPLY",
+	OP_DIVIDE:        "OP_DIVIDE",
+	OP_MODULO:        "OP_MODULO",
+	OP_INT_DIVIDE:    "OP_INT_DIVIDE",
+	OP_POWER:         "OP_POWER",
+	OP_NOT:           "OP_NOT",
+	OP_NEGATE:        "OP_NEGATE",
+	OP_STRINGIFY:     "OP_STRINGIFY",
+	OP_PRINT:         "OP_PRINT",
+	OP_JUMP:          "OP_JUMP",
+	OP_JUMP_IF_FALSE: "OP_JUMP_IF_FALSE",
+	OP_JUMP_IF_GIVEN: "OP_JUMP_IF_GIVEN",
+	OP_LOOP:          "OP_LOOP",
+	OP_ITERATOR:      "OP_ITERATOR",
+	OP_FOR_ITER:      "OP_FOR_ITER",
+	OP_CALL:          "OP_CALL",
+	OP_CALL_KEYWORDS: "OP_CALL_KEYWORDS",
+	OP_CLOSURE:       "OP_CLOSURE",
+	OP_CLOSE_UPVALUE: "OP_CLOSE_UPVALUE",
+	OP_RETURN:        "OP_RETURN",
+	OP_YIELD:         "OP_YIELD",
+	OP_THROW:         "OP_THROW",
+	OP_TRY:           "OP_TRY",
+	OP_POP_HANDLER:   "OP_POP_HANDLER",
+	OP_CATCH:         "OP_CATCH",
+	OP_RETHROW:       "OP_RETHROW",
+	OP_IMPORT:        "OP_IMPORT",
+	OP_CLASS:         "OP_CLASS",
+	OP_INHERIT:       "OP_INHERIT",
+	OP_METHOD:        "OP_METHOD",
+	OP_CLASS_METHOD:  "OP_CLASS_METHOD",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) && opNames[op] != "" {
+		return opNames[op]
+	}
+	return fmt.Sprintf("OP_%d", op)
+}
+
+// Chunk is a sequence of bytecode together with its constant pool. Every
+// byte of code records the source token it was compiled from, which
+// locates runtime errors.
+type Chunk struct {
+	Code      []byte
+	Tokens    []*Token
+	Constants []any
+}
+
+// keywordArguments are the names of the keyword arguments of a call.
+type keywordArguments struct {
+	names []string
+}
+
+func (k *keywordArguments) String() string {
+	return strings.Join(k.names, ", ")
+}
+
+func (c *Chunk) Write(b byte, token *Token) {
+	c.Code = append(c.Code, b)
+	c.Tokens = append(c.Tokens, token)
+}
+
+func (c *Chunk) AddConstant(value any) int {
+	for k, constant := range c.Constants {
+		if constant == value {
+			return k
+		}
+	}
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
+
+// Disassemble writes a human readable listing of the chunk to w.
+func (c *Chunk) Disassemble(w io.Writer, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
+	for offset := 0; offset < len(c.Code); {
+		offset = c.disassembleInstruction(w, offset)
+	}
+}
+
+func (c *Chunk) disassembleInstruction(w io.Writer, offset int) int {
+	line := 0
+	if token := c.Tokens[offset]; token != nil {
+		line = token.Line
+	}
+	fmt.Fprintf(w, "%04d %4d ", offset, line)
+
+	op := OpCode(c.Code[offset])
+	switch op {
+	case OP_CONSTANT, OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL,
+		OP_GET_PROPERTY, OP_SET_PROPERTY, OP_GET_SUPER, OP_IMPORT, OP_CLASS, OP_METHOD,
+		OP_CLASS_METHOD:
+		constant := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-16s %4d '%v'\n", op, constant, c.Constants[constant])
+		return offset + 3
+	case OP_LIST, OP_MAP:
+		fmt.Fprintf(w, "%-16s %4d\n", op, c.readShort(offset+1))
+		return offset + 3
+	case OP_DUP, OP_BURY, OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CALL:
+		fmt.Fprintf(w, "%-16s %4d\n", op, c.Code[offset+1])
+		return offset + 2
+	case OP_CALL_KEYWORDS:
+		constant := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-16s %4d '%v' %d\n", op, constant, c.Constants[constant], c.Code[offset+3])
+		return offset + 4
+	case OP_JUMP, OP_JUMP_IF_FALSE, OP_JUMP_IF_GIVEN, OP_FOR_ITER, OP_TRY:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, offset+3+jump)
+		return offset + 3
+	case OP_LOOP:
+		jump := c.readShort(offset + 1)
+		fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, offset+3-jump)
+		return offset + 3
+	case OP_CLOSURE:
+		constant := c.readShort(offset + 1)
+		function := c.Constants[constant].(*Prototype)
+		fmt.Fprintf(w, "%-16s %4d %v\n", op, constant, function)
+		offset += 3
+		for k := 0; k < function.UpvalueCount; k++ {
+			kind := "upvalue"
+			if c.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(w, "%04d      |                     %s %d\n",
+				offset, kind, c.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	default:
+		fmt.Fprintf(w, "%s\n", op)
+		return offset + 1
+	}
+}
+
+func (c *Chunk) readShort(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
@@ -1,13 +1,30 @@
 package lox
 
+import "fmt"
+
+// Method is a function declared in a class body. Each execution engine
+// provides its own implementation. The receiver is an instance, or the
+// class itself for class methods. Getters are methods declared without a
+// parameter list, which run when the property is read.
+type Method interface {
+	Callable
+	Bind(receiver any) Method
+	IsGetter() bool
+}
+
+// LoxClass is a class. Besides creating instances, a class is an object
+// whose properties are its class methods and class fields, both of which
+// are inherited by subclasses.
 type LoxClass struct {
-	Name       string
-	Methods    map[string]*LoxFunction
-	Superclass *LoxClass
+	Name         string
+	Methods      map[string]Method
+	Superclass   *LoxClass
+	ClassMethods map[string]Method
+	Fields       map[string]any
 }
 
-func NewLoxClass(name string, superclass *LoxClass, methods map[string]*LoxFunction) *LoxClass {
-	return &LoxClass{name, methods, superclass}
+func NewLoxClass(name string, superclass *LoxClass, methods map[string]Method) *LoxClass {
+	return &LoxClass{name, methods, superclass, make(map[string]Method), make(map[string]any)}
 }
 
 func (c *LoxClass) String() string {
@@ -25,15 +42,55 @@ func (c *LoxClass) Call(interpreter *Interpreter, arguments []any) any {
 	return instance
 }
 
-func (c *LoxClass) Arity() int {
+func (c *LoxClass) Arity() (int, int) {
 	initializer := c.FindMethod("init")
 	if initializer == nil {
-		return 0
+		return 0, 0
 	}
 	return initializer.Arity()
 }
 
-func (c *LoxClass) FindMethod(name string) *LoxFunction {
+func (c *LoxClass) Parameters() []string {
+	if initializer, ok := c.FindMethod("init").(KeywordCallable); ok {
+		return initializer.Parameters()
+	}
+	return nil
+}
+
+func (c *LoxClass) Get(name *Token) any {
+	for class := c; class != nil; class = class.Superclass {
+		if val, ok := class.Fields[name.Lexeme]; ok {
+			return val
+		}
+	}
+
+	method := c.FindClassMethod(name.Lexeme)
+	if method != nil {
+		return method.Bind(c)
+	}
+
+	panic(NewRuntimeError(name,
+		fmt.Sprintf("Undefined property '%s'.", name.Lexeme)))
+}
+
+// Set assigns a field of the class, shadowing one it inherits.
+func (c *LoxClass) Set(name *Token, value any) {
+	c.Fields[name.Lexeme] = value
+}
+
+func (c *LoxClass) FindClassMethod(name string) Method {
+	if val, ok := c.ClassMethods[name]; ok {
+		return val
+	}
+
+	if c.Superclass != nil {
+		return c.Superclass.FindClassMethod(name)
+	}
+
+	return nil
+}
+
+func (c *LoxClass) FindMethod(name string) Method {
 	if val, ok := c.Methods[name]; ok {
 		return val
 	}
@@ -0,0 +1,131 @@
+package lox
+
+import "fmt"
+
+// Prototype is a function compiled to bytecode. Closures created from it
+// at runtime share its chunk.
+type Prototype struct {
+	Name          string
+	Arity         int
+	UpvalueCount  int
+	IsInitializer bool
+	IsGetter      bool
+	IsGenerator   bool
+	Chunk         *Chunk
+	// The first Required of the Arity parameters have no default. Rest
+	// is set if further arguments are collected in a list.
+	Required int
+	Rest     bool
+	Params   []string
+}
+
+func NewPrototype(name string) *Prototype {
+	return &Prototype{Name: name, Chunk: &Chunk{}}
+}
+
+func (p *Prototype) String() string {
+	if p.Name == "" {
+		return "<script>"
+	}
+	return fmt.Sprintf("<fn %s>", p.Name)
+}
+
+// Upvalue is a variable captured by a closure. While the variable is still
+// on the VM stack the upvalue refers to its slot; once the variable goes
+// out of scope the value moves into the upvalue itself.
+type Upvalue struct {
+	vm     *VM
+	slot   int
+	closed any
+	open   bool
+	next   *Upvalue
+}
+
+func (u *Upvalue) Get() any {
+	if u.open {
+		return u.vm.stack[u.slot]
+	}
+	return u.closed
+}
+
+func (u *Upvalue) Set(value any) {
+	if u.open {
+		u.vm.stack[u.slot] = value
+	} else {
+		u.closed = value
+	}
+}
+
+// Closure is a function value of the bytecode VM.
+type Closure struct {
+	Function *Prototype
+	Upvalues []*Upvalue
+	Globals  *Environment
+	Class    *LoxClass
+}
+
+func NewClosure(function *Prototype, globals *Environment) *Closure {
+	return &Closure{function, make([]*Upvalue, function.UpvalueCount), globals, nil}
+}
+
+func (c *Closure) Arity() (int, int) {
+	if c.Function.Rest {
+		return c.Function.Required, Variadic
+	}
+	return c.Function.Required, c.Function.Arity
+}
+
+func (c *Closure) Parameters() []string {
+	return c.Function.Params
+}
+
+func (c *Closure) Call(interpreter *Interpreter, arguments []any) any {
+	return interpreter.vm.call(c, nil, arguments)
+}
+
+func (c *Closure) Bind(receiver any) Method {
+	return NewBoundMethod(receiver, c)
+}
+
+func (c *Closure) IsGetter() bool {
+	return c.Function.IsGetter
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+// BoundMethod is a closure declared in a class body together with the
+// instance or class it was accessed on.
+type BoundMethod struct {
+	Receiver any
+	Method   *Closure
+}
+
+func NewBoundMethod(receiver any, method *Closure) *BoundMethod {
+	return &BoundMethod{receiver, method}
+}
+
+func (b *BoundMethod) Arity() (int, int) {
+	return b.Method.Arity()
+}
+
+func (b *BoundMethod) Parameters() []string {
+	return b.Method.Parameters()
+}
+
+func (b *BoundMethod) Call(interpreter *Interpreter, arguments []any) any {
+	return interpreter.vm.call(b.Method, b.Receiver, arguments)
+}
+
+func (b *BoundMethod) Bind(receiver any) Method {
+	return NewBoundMethod(receiver, b.Method)
+}
+
+func (b *BoundMethod) IsGetter() bool {
+	return b.Method.IsGetter()
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
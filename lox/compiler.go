@@ -0,0 +1,1017 @@
+package lox
+
+import "math"
+
+// The limits of the bytecode format.
+const (
+	maxLocals    = math.MaxUint8 + 1
+	maxUpvalues  = math.MaxUint8 + 1
+	maxConstants = math.MaxUint16 + 1
+	maxJump      = math.MaxUint16
+	maxElements  = math.MaxUint16
+)
+
+type local struct {
+	name     string
+	depth    int
+	captured bool
+}
+
+type upvalueRef struct {
+	index   int
+	isLocal bool
+}
+
+// loop collects the jumps of break and continue statements in a loop body
+// until their targets are known.
+type loop struct {
+	scopeDepth int
+	breaks     []int
+	continues  []int
+}
+
+// guard is code protected by a try statement. Statements that jump out of
+// it pop its handler and run its finally block on the way.
+type guard struct {
+	loops   int
+	finally []Stmt
+}
+
+// functionState tracks the function being compiled. Slot zero of every
+// function holds the callee, or the receiver for methods.
+type functionState struct {
+	enclosing  *functionState
+	function   *Prototype
+	kind       int
+	locals     []local
+	upvalues   []upvalueRef
+	loops      []*loop
+	guards     []*guard
+	scopeDepth int
+}
+
+func newFunctionState(enclosing *functionState, function *Prototype, kind int) *functionState {
+	receiver := ""
+	if kind == FN_METHOD || kind == FN_INITIALIZER {
+		receiver = "this"
+	}
+	return &functionState{
+		enclosing: enclosing,
+		function:  function,
+		kind:      kind,
+		locals:    []local{{receiver, 0, false}},
+	}
+}
+
+// Compiler translates resolved statements into bytecode for the VM. It
+// relies on the Resolver having reported semantic errors already.
+type Compiler struct {
+	lox     *Lox
+	current *functionState
+	token   *Token
+}
+
+func NewCompiler(lox *Lox) *Compiler {
+	return &Compiler{lox: lox}
+}
+
+func (c *Compiler) Compile(statements []Stmt) *Prototype {
+	c.current = newFunctionState(nil, NewPrototype(""), FN_NONE)
+	c.token = NewToken(EOF, "", nil, 1)
+	for _, statement := range statements {
+		c.declaration(statement)
+	}
+	c.emitReturn()
+	return c.current.function
+}
+
+func (c *Compiler) declaration(stmt Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			compileError, ok := r.(*CompileError)
+			if !ok {
+				panic(r)
+			}
+			c.lox.Report(compileError)
+		}
+	}()
+
+	c.statement(stmt)
+}
+
+func (c *Compiler) statement(stmt Stmt) {
+	previous := c.token
+	if token := stmtToken(stmt); token != nil {
+		c.token = token
+	}
+	stmt.Accept(c)
+	c.token = previous
+}
+
+func (c *Compiler) expression(expr Expr) {
+	previous := c.token
+	if token := exprToken(expr); token != nil {
+		c.token = token
+	}
+	expr.Accept(c)
+	c.token = previous
+}
+
+func (c *Compiler) VisitBlockStmt(stmt *Block) any {
+	c.block(stmt.Statements)
+	return nil
+}
+
+func (c *Compiler) VisitClassStmt(stmt *Class) any {
+	name := c.identifierConstant(stmt.Name.Lexeme)
+	c.declareVariable(stmt.Name)
+
+	c.emitShort(OP_CLASS, name)
+	c.defineVariable(name)
+
+	if stmt.Superclass != nil {
+		c.expression(stmt.Superclass)
+
+		c.beginScope()
+		c.addLocal("super")
+		c.markInitialized()
+
+		c.namedVariable(stmt.Name, false)
+		c.token = stmt.Superclass.Name
+		c.emit(OP_INHERIT)
+		c.token = stmt.Name
+	}
+
+	c.namedVariable(stmt.Name, false)
+	for _, method := range stmt.Methods {
+		kind := FN_METHOD
+		if method.Name.Lexeme == "init" {
+			kind = FN_INITIALIZER
+		}
+		c.function(method, kind)
+		c.token = method.Name
+		c.emitShort(OP_METHOD, c.identifierConstant(method.Name.Lexeme))
+	}
+	for _, method := range stmt.ClassMethods {
+		c.function(method, FN_METHOD)
+		c.token = method.Name
+		c.emitShort(OP_CLASS_METHOD, c.identifierConstant(method.Name.Lexeme))
+	}
+	c.emit(OP_POP)
+
+	if stmt.Superclass != nil {
+		c.endScope()
+	}
+
+	for _, field := range stmt.Fields {
+		c.token = field.Name
+		c.namedVariable(stmt.Name, false)
+		if field.Initializer != nil {
+			c.expression(field.Initializer)
+		} else {
+			c.emit(OP_NIL)
+		}
+		c.emitShort(OP_SET_PROPERTY, c.identifierConstant(field.Name.Lexeme))
+		c.emit(OP_POP)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitExpressionStmt(stmt *Expression) any {
+	c.expression(stmt.Expression)
+	c.emit(OP_POP)
+	return nil
+}
+
+func (c *Compiler) VisitFunctionStmt(stmt *Function) any {
+	c.declareVariable(stmt.Name)
+	c.markInitialized()
+	c.function(stmt, FN_FUNCTION)
+	c.defineVariable(c.identifierConstant(stmt.Name.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitIfStmt(stmt *If) any {
+	c.expression(stmt.Condition)
+
+	thenJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+	c.statement(stmt.ThenBranch)
+
+	elseJump := c.emitJump(OP_JUMP)
+	c.patchJump(thenJump)
+	c.emit(OP_POP)
+
+	if stmt.ElseBranch != nil {
+		c.statement(stmt.ElseBranch)
+	}
+	c.patchJump(elseJump)
+	return nil
+}
+
+// VisitMatchStmt keeps the value in an unnamed local while the cases test
+// it in order.
+func (c *Compiler) VisitMatchStmt(stmt *Match) any {
+	c.beginScope()
+	c.expression(stmt.Value)
+	c.addLocal("")
+	c.markInitialized()
+	value := len(c.current.locals) - 1
+
+	var exits []int
+	for k, patterns := range stmt.Patterns {
+		var matched []int
+		for _, pattern := range patterns {
+			c.emitByte(OP_GET_LOCAL, value)
+			c.expression(pattern)
+			c.emit(OP_MATCH)
+			next := c.emitJump(OP_JUMP_IF_FALSE)
+			c.emit(OP_POP)
+			matched = append(matched, c.emitJump(OP_JUMP))
+			c.patchJump(next)
+			c.emit(OP_POP)
+		}
+		nextCase := c.emitJump(OP_JUMP)
+
+		for _, jump := range matched {
+			c.patchJump(jump)
+		}
+		var guardFailed int
+		if stmt.Guards[k] != nil {
+			c.expression(stmt.Guards[k])
+			guardFailed = c.emitJump(OP_JUMP_IF_FALSE)
+			c.emit(OP_POP)
+		}
+		c.statement(stmt.Bodies[k])
+		exits = append(exits, c.emitJump(OP_JUMP))
+		if stmt.Guards[k] != nil {
+			c.patchJump(guardFailed)
+			c.emit(OP_POP)
+		}
+
+		c.patchJump(nextCase)
+	}
+
+	if stmt.Otherwise != nil {
+		c.statement(stmt.Otherwise)
+	}
+	for _, exit := range exits {
+		c.patchJump(exit)
+	}
+	c.endScope()
+	return nil
+}
+
+func (c *Compiler) VisitPrintStmt(stmt *Print) any {
+	c.expression(stmt.Expression)
+	c.emit(OP_PRINT)
+	return nil
+}
+
+func (c *Compiler) VisitReturnStmt(stmt *Return) any {
+	if stmt.Value == nil {
+		c.unwind(0)
+		c.emitReturn()
+		return nil
+	}
+
+	c.expression(stmt.Value)
+	if len(c.current.guards) > 0 {
+		c.hiddenLocals(1, func() {
+			c.unwind(0)
+			c.emitByte(OP_GET_LOCAL, len(c.current.locals)-1)
+		})
+	}
+	c.emit(OP_RETURN)
+	return nil
+}
+
+func (c *Compiler) VisitYieldStmt(stmt *Yield) any {
+	if stmt.Value != nil {
+		c.expression(stmt.Value)
+	} else {
+		c.emit(OP_NIL)
+	}
+	c.token = stmt.Keyword
+	c.emit(OP_YIELD)
+	return nil
+}
+
+func (c *Compiler) VisitThrowStmt(stmt *Throw) any {
+	c.expression(stmt.Value)
+	c.emit(OP_THROW)
+	return nil
+}
+
+// VisitTryStmt compiles the finally block into every way out of the try
+// statement. Errors raised in the body land after it with the error on
+// the stack; errors raised in the catch clause land on code that runs the
+// finally block and throws them again.
+func (c *Compiler) VisitTryStmt(stmt *Try) any {
+	bodyHandler := c.emitJump(OP_TRY)
+	c.guarded(stmt.Finally, func() {
+		c.block(stmt.Body)
+	})
+	c.emit(OP_POP_HANDLER)
+	c.finally(stmt.Finally)
+	exits := []int{c.emitJump(OP_JUMP)}
+	c.patchJump(bodyHandler)
+
+	if stmt.Name == nil {
+		c.rethrow(stmt.Finally, 1)
+	} else {
+		c.beginScope()
+		c.emit(OP_CATCH)
+		c.addLocal(stmt.Name.Lexeme)
+		c.markInitialized()
+		if stmt.Finally == nil {
+			for _, statement := range stmt.Handler {
+				c.statement(statement)
+			}
+			c.endScope()
+		} else {
+			catchHandler := c.emitJump(OP_TRY)
+			c.guarded(stmt.Finally, func() {
+				for _, statement := range stmt.Handler {
+					c.statement(statement)
+				}
+			})
+			c.emit(OP_POP_HANDLER)
+			c.endScope()
+			c.finally(stmt.Finally)
+			exits = append(exits, c.emitJump(OP_JUMP))
+			c.patchJump(catchHandler)
+			c.rethrow(stmt.Finally, 2)
+		}
+	}
+
+	for _, exit := range exits {
+		c.patchJump(exit)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitVarStmt(stmt *Var) any {
+	c.declareVariable(stmt.Name)
+	if stmt.Initializer != nil {
+		c.expression(stmt.Initializer)
+	} else {
+		c.emit(OP_NIL)
+	}
+	c.defineVariable(c.identifierConstant(stmt.Name.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitWhileStmt(stmt *While) any {
+	state := c.current
+	loopStart := len(c.chunk().Code)
+	c.expression(stmt.Condition)
+
+	exitJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+
+	current := &loop{scopeDepth: state.scopeDepth}
+	state.loops = append(state.loops, current)
+	c.statement(stmt.Body)
+	state.loops = state.loops[:len(state.loops)-1]
+
+	for _, jump := range current.continues {
+		c.patchJump(jump)
+	}
+	if stmt.Increment != nil {
+		c.expression(stmt.Increment)
+		c.emit(OP_POP)
+	}
+	c.emitLoop(loopStart)
+
+	c.patchJump(exitJump)
+	c.emit(OP_POP)
+	for _, jump := range current.breaks {
+		c.patchJump(jump)
+	}
+	return nil
+}
+
+// VisitForInStmt keeps the iterator in a hidden local and declares the
+// loop variable in a scope of its own, which is closed after every
+// iteration so that closures capture the value of their iteration.
+func (c *Compiler) VisitForInStmt(stmt *ForIn) any {
+	state := c.current
+	c.beginScope()
+	c.expression(stmt.Iterable)
+	c.token = stmt.Keyword
+	c.emit(OP_ITERATOR)
+	c.addLocal("")
+	c.markInitialized()
+
+	loopStart := len(c.chunk().Code)
+	exitJump := c.emitJump(OP_FOR_ITER)
+
+	current := &loop{scopeDepth: state.scopeDepth}
+	state.loops = append(state.loops, current)
+	c.beginScope()
+	c.declareVariable(stmt.Name)
+	c.markInitialized()
+	c.statement(stmt.Body)
+	c.endScope()
+	state.loops = state.loops[:len(state.loops)-1]
+
+	for _, jump := range current.continues {
+		c.patchJump(jump)
+	}
+	c.emitLoop(loopStart)
+
+	c.patchJump(exitJump)
+	for _, jump := range current.breaks {
+		c.patchJump(jump)
+	}
+	c.endScope()
+	return nil
+}
+
+func (c *Compiler) VisitExportStmt(stmt *Export) any {
+	c.statement(stmt.Declaration)
+	return nil
+}
+
+func (c *Compiler) VisitImportStmt(stmt *Import) any {
+	path := c.makeConstant(stmt.Path.Literal.(string))
+	if stmt.Alias != nil {
+		c.declareVariable(stmt.Alias)
+		c.emitShort(OP_IMPORT, path)
+		c.defineVariable(c.identifierConstant(stmt.Alias.Lexeme))
+	}
+	for _, name := range stmt.Names {
+		c.declareVariable(name)
+		c.emitShort(OP_IMPORT, path)
+		c.token = name
+		c.emitShort(OP_GET_PROPERTY, c.identifierConstant(name.Lexeme))
+		c.defineVariable(c.identifierConstant(name.Lexeme))
+		c.token = stmt.Keyword
+	}
+	return nil
+}
+
+func (c *Compiler) VisitBreakStmt(stmt *Break) any {
+	current := c.current.loops[len(c.current.loops)-1]
+	c.unwind(len(c.current.loops))
+	c.discardLocals(current.scopeDepth)
+	current.breaks = append(current.breaks, c.emitJump(OP_JUMP))
+	return nil
+}
+
+func (c *Compiler) VisitContinueStmt(stmt *Continue) any {
+	current := c.current.loops[len(c.current.loops)-1]
+	c.unwind(len(c.current.loops))
+	c.discardLocals(current.scopeDepth)
+	current.continues = append(current.continues, c.emitJump(OP_JUMP))
+	return nil
+}
+
+func (c *Compiler) VisitAssignExpr(expr *Assign) any {
+	c.expression(expr.Value)
+	c.namedVariable(expr.Name, true)
+	return nil
+}
+
+var binaryOps = map[TokenType]OpCode{
+	BANG_EQUAL:    OP_NOT_EQUAL,
+	EQUAL_EQUAL:   OP_EQUAL,
+	GREATER:       OP_GREATER,
+	GREATER_EQUAL: OP_GREATER_EQUAL,
+	LESS:          OP_LESS,
+	LESS_EQUAL:    OP_LESS_EQUAL,
+	PLUS:          OP_ADD,
+	MINUS:         OP_SUBTRACT,
+	STAR:          OP_MULTIPLY,
+	SLASH:         OP_DIVIDE,
+	PERCENT:       OP_MODULO,
+	TILDE_SLASH:   OP_INT_DIVIDE,
+	STAR_STAR:     OP_POWER,
+}
+
+func (c *Compiler) VisitBinaryExpr(expr *Binary) any {
+	c.expression(expr.Left)
+	c.expression(expr.Right)
+	c.emit(binaryOps[expr.Operator.Type])
+	return nil
+}
+
+func (c *Compiler) VisitCallExpr(expr *Call) any {
+	c.expression(expr.Callee)
+	for _, argument := range expr.Arguments {
+		c.expression(argument)
+	}
+	if len(expr.Names) > 0 {
+		c.token = expr.Paren
+		c.emitShort(OP_CALL_KEYWORDS, c.makeConstant(&keywordArguments{keywordNames(expr.Names)}))
+		c.chunk().Write(byte(len(expr.Arguments)), c.token)
+		return nil
+	}
+	c.emitByte(OP_CALL, len(expr.Arguments))
+	return nil
+}
+
+// VisitCompoundExpr duplicates the evaluated parts of the target to both
+// read and store it. A postfix expression buries a copy of the old value
+// beneath them as its result.
+func (c *Compiler) VisitCompoundExpr(expr *Compound) any {
+	update := func(parts int) {
+		if expr.Postfix {
+			c.emitByte(OP_DUP, 1)
+			c.emitByte(OP_BURY, parts+1)
+		}
+		c.expression(expr.Value)
+		c.emit(binaryOps[expr.Operator.Type])
+	}
+
+	switch target := expr.Target.(type) {
+	case *Variable:
+		c.namedVariable(target.Name, false)
+		update(0)
+		c.namedVariable(target.Name, true)
+	case *Get:
+		c.expression(target.Object)
+		c.emitByte(OP_DUP, 1)
+		name := c.identifierConstant(target.Name.Lexeme)
+		c.token = target.Name
+		c.emitShort(OP_GET_PROPERTY, name)
+		c.token = expr.Operator
+		update(1)
+		c.token = target.Name
+		c.emitShort(OP_SET_PROPERTY, name)
+	case *Index:
+		c.expression(target.Object)
+		c.expression(target.Index)
+		c.emitByte(OP_DUP, 2)
+		c.token = target.Bracket
+		c.emit(OP_GET_INDEX)
+		c.token = expr.Operator
+		update(2)
+		c.token = target.Bracket
+		c.emit(OP_SET_INDEX)
+	}
+
+	if expr.Postfix {
+		c.emit(OP_POP)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitConditionalExpr(expr *Conditional) any {
+	c.expression(expr.Condition)
+
+	elseJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emit(OP_POP)
+	c.expression(expr.ThenBranch)
+
+	endJump := c.emitJump(OP_JUMP)
+	c.patchJump(elseJump)
+	c.emit(OP_POP)
+	c.expression(expr.ElseBranch)
+	c.patchJump(endJump)
+	return nil
+}
+
+func (c *Compiler) VisitGetExpr(expr *Get) any {
+	c.expression(expr.Object)
+	c.emitShort(OP_GET_PROPERTY, c.identifierConstant(expr.Name.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitGroupingExpr(expr *Grouping) any {
+	c.expression(expr.Expression)
+	return nil
+}
+
+func (c *Compiler) VisitIndexExpr(expr *Index) any {
+	c.expression(expr.Object)
+	c.expression(expr.Index)
+	c.emit(OP_GET_INDEX)
+	return nil
+}
+
+func (c *Compiler) VisitLambdaExpr(expr *Lambda) any {
+	c.function(expr.Function, FN_FUNCTION)
+	return nil
+}
+
+func (c *Compiler) VisitListExpr(expr *List) any {
+	if len(expr.Elements) > maxElements {
+		panic(NewCompileError(c.token, "Too many elements in list literal."))
+	}
+	for _, element := range expr.Elements {
+		c.expression(element)
+	}
+	c.emitShort(OP_LIST, len(expr.Elements))
+	return nil
+}
+
+func (c *Compiler) VisitLiteralExpr(expr *Literal) any {
+	switch expr.Value {
+	case nil:
+		c.emit(OP_NIL)
+	case true:
+		c.emit(OP_TRUE)
+	case false:
+		c.emit(OP_FALSE)
+	default:
+		c.emitShort(OP_CONSTANT, c.makeConstant(expr.Value))
+	}
+	return nil
+}
+
+func (c *Compiler) VisitLogicalExpr(expr *Logical) any {
+	c.expression(expr.Left)
+
+	if expr.Operator.Type == OR {
+		elseJump := c.emitJump(OP_JUMP_IF_FALSE)
+		endJump := c.emitJump(OP_JUMP)
+		c.patchJump(elseJump)
+		c.emit(OP_POP)
+		c.expression(expr.Right)
+		c.patchJump(endJump)
+	} else {
+		endJump := c.emitJump(OP_JUMP_IF_FALSE)
+		c.emit(OP_POP)
+		c.expression(expr.Right)
+		c.patchJump(endJump)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitMapExpr(expr *Map) any {
+	if len(expr.Keys) > maxElements {
+		panic(NewCompileError(c.token, "Too many entries in map literal."))
+	}
+	for k := range expr.Keys {
+		c.expression(expr.Keys[k])
+		c.expression(expr.Values[k])
+	}
+	c.emitShort(OP_MAP, len(expr.Keys))
+	return nil
+}
+
+func (c *Compiler) VisitSetExpr(expr *Set) any {
+	c.expression(expr.Object)
+	c.expression(expr.Value)
+	c.emitShort(OP_SET_PROPERTY, c.identifierConstant(expr.Name.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitSetIndexExpr(expr *SetIndex) any {
+	c.expression(expr.Object)
+	c.expression(expr.Index)
+	c.expression(expr.Value)
+	c.emit(OP_SET_INDEX)
+	return nil
+}
+
+func (c *Compiler) VisitStringifyExpr(expr *Stringify) any {
+	c.expression(expr.Expression)
+	c.emit(OP_STRINGIFY)
+	return nil
+}
+
+func (c *Compiler) VisitSuperExpr(expr *Super) any {
+	c.namedVariable(NewToken(THIS, "this", nil, expr.Keyword.Line), false)
+	c.namedVariable(expr.Keyword, false)
+	c.token = expr.Method
+	c.emitShort(OP_GET_SUPER, c.identifierConstant(expr.Method.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitThisExpr(expr *This) any {
+	c.namedVariable(expr.Keyword, false)
+	return nil
+}
+
+func (c *Compiler) VisitUnaryExpr(expr *Unary) any {
+	c.expression(expr.Right)
+	switch expr.Operator.Type {
+	case MINUS:
+		c.emit(OP_NEGATE)
+	case BANG:
+		c.emit(OP_NOT)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitVariableExpr(expr *Variable) any {
+	c.namedVariable(expr.Name, false)
+	return nil
+}
+
+func (c *Compiler) function(declaration *Function, kind int) {
+	function := NewPrototype(functionName(declaration))
+	function.IsInitializer = kind == FN_INITIALIZER
+	function.IsGetter = declaration.Params == nil
+	function.IsGenerator = declaration.Generator
+	function.Required, _ = functionArity(declaration)
+	function.Rest = declaration.Rest != nil
+	function.Params = parameterNames(declaration)
+	c.current = newFunctionState(c.current, function, kind)
+	c.beginScope()
+
+	for k, param := range declaration.Params {
+		function.Arity++
+		if declaration.Defaults[k] != nil {
+			c.defaultValue(k+1, declaration.Defaults[k])
+		}
+		c.declareVariable(param)
+		c.markInitialized()
+	}
+	if declaration.Rest != nil {
+		c.declareVariable(declaration.Rest)
+		c.markInitialized()
+	}
+	for _, statement := range declaration.Body {
+		c.statement(statement)
+	}
+	c.emitReturn()
+
+	state := c.current
+	c.current = state.enclosing
+
+	if declaration.Name != nil {
+		c.token = declaration.Name
+	}
+	c.emitShort(OP_CLOSURE, c.makeConstant(function))
+	for _, upvalue := range state.upvalues {
+		isLocal := 0
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.chunk().Write(byte(isLocal), c.token)
+		c.chunk().Write(byte(upvalue.index), c.token)
+	}
+}
+
+// defaultValue stores value in the parameter in slot if its argument was
+// left out. It is compiled before the parameter is declared, so that it
+// only sees the parameters before it.
+func (c *Compiler) defaultValue(slot int, value Expr) {
+	c.emitByte(OP_GET_LOCAL, slot)
+	jump := c.emitJump(OP_JUMP_IF_GIVEN)
+	c.expression(value)
+	c.emitByte(OP_SET_LOCAL, slot)
+	c.emit(OP_POP)
+	c.patchJump(jump)
+}
+
+func (c *Compiler) block(statements []Stmt) {
+	c.beginScope()
+	for _, statement := range statements {
+		c.statement(statement)
+	}
+	c.endScope()
+}
+
+// guarded compiles the code protected by a handler of a try statement
+// with the given finally block.
+func (c *Compiler) guarded(finally []Stmt, fn func()) {
+	state := c.current
+	state.guards = append(state.guards, &guard{len(state.loops), finally})
+	fn()
+	state.guards = state.guards[:len(state.guards)-1]
+}
+
+// unwind emits the code that leaves the guards entered inside the first
+// loops loops of the function, innermost first. A finally block is
+// compiled as if only the guards around it were active.
+func (c *Compiler) unwind(loops int) {
+	state := c.current
+	guards := state.guards
+	for k := len(guards) - 1; k >= 0 && guards[k].loops >= loops; k-- {
+		c.emit(OP_POP_HANDLER)
+		state.guards = guards[:k]
+		c.finally(guards[k].finally)
+	}
+	state.guards = guards
+}
+
+func (c *Compiler) finally(finally []Stmt) {
+	if finally != nil {
+		c.block(finally)
+	}
+}
+
+// rethrow compiles the landing that runs a finally block for an error
+// escaping the try statement and then throws the error again. count is
+// the number of stack slots above the locals, the error being the last.
+func (c *Compiler) rethrow(finally []Stmt, count int) {
+	c.hiddenLocals(count, func() {
+		c.finally(finally)
+		c.emitByte(OP_GET_LOCAL, len(c.current.locals)-1)
+		c.emit(OP_RETHROW)
+	})
+}
+
+// hiddenLocals declares the count values on top of the stack as unnamed
+// locals while fn compiles code that doesn't fall through, so that
+// locals declared by fn get the right slots.
+func (c *Compiler) hiddenLocals(count int, fn func()) {
+	state := c.current
+	locals := len(state.locals)
+	state.scopeDepth++
+	for range count {
+		c.addLocal("")
+		c.markInitialized()
+	}
+	fn()
+	state.scopeDepth--
+	state.locals = state.locals[:locals]
+}
+
+// namedVariable emits a load of the variable called name, or a store of
+// the value on top of the stack into it.
+func (c *Compiler) namedVariable(name *Token, assign bool) {
+	getOp, setOp := OP_GET_GLOBAL, OP_SET_GLOBAL
+	arg := c.resolveLocal(c.current, name.Lexeme)
+	if arg != -1 {
+		getOp, setOp = OP_GET_LOCAL, OP_SET_LOCAL
+	} else if arg = c.resolveUpvalue(c.current, name.Lexeme); arg != -1 {
+		getOp, setOp = OP_GET_UPVALUE, OP_SET_UPVALUE
+	} else {
+		arg = c.identifierConstant(name.Lexeme)
+	}
+
+	op := getOp
+	if assign {
+		op = setOp
+	}
+
+	previous := c.token
+	c.token = name
+	if op == OP_GET_GLOBAL || op == OP_SET_GLOBAL {
+		c.emitShort(op, arg)
+	} else {
+		c.emitByte(op, arg)
+	}
+	c.token = previous
+}
+
+func (c *Compiler) resolveLocal(state *functionState, name string) int {
+	for k := len(state.locals) - 1; k >= 0; k-- {
+		if state.locals[k].name == name {
+			return k
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) resolveUpvalue(state *functionState, name string) int {
+	if state.enclosing == nil {
+		return -1
+	}
+
+	if local := c.resolveLocal(state.enclosing, name); local != -1 {
+		state.enclosing.locals[local].captured = true
+		return c.addUpvalue(state, local, true)
+	}
+
+	if upvalue := c.resolveUpvalue(state.enclosing, name); upvalue != -1 {
+		return c.addUpvalue(state, upvalue, false)
+	}
+
+	return -1
+}
+
+func (c *Compiler) addUpvalue(state *functionState, index int, isLocal bool) int {
+	for k, upvalue := range state.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return k
+		}
+	}
+
+	if len(state.upvalues) == maxUpvalues {
+		panic(NewCompileError(c.token, "Too many closure variables in function."))
+	}
+
+	state.upvalues = append(state.upvalues, upvalueRef{index, isLocal})
+	state.function.UpvalueCount = len(state.upvalues)
+	return len(state.upvalues) - 1
+}
+
+func (c *Compiler) declareVariable(name *Token) {
+	if c.current.scopeDepth == 0 {
+		return
+	}
+	c.addLocal(name.Lexeme)
+}
+
+func (c *Compiler) addLocal(name string) {
+	if len(c.current.locals) == maxLocals {
+		panic(NewCompileError(c.token, "Too many local variables in function."))
+	}
+	c.current.locals = append(c.current.locals, local{name, -1, false})
+}
+
+func (c *Compiler) markInitialized() {
+	if c.current.scopeDepth == 0 {
+		return
+	}
+	c.current.locals[len(c.current.locals)-1].depth = c.current.scopeDepth
+}
+
+// defineVariable binds the value on top of the stack to the variable
+// declared last. Locals simply stay on the stack.
+func (c *Compiler) defineVariable(global int) {
+	if c.current.scopeDepth > 0 {
+		c.markInitialized()
+		return
+	}
+	c.emitShort(OP_DEFINE_GLOBAL, global)
+}
+
+func (c *Compiler) beginScope() {
+	c.current.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	state := c.current
+	state.scopeDepth--
+
+	c.discardLocals(state.scopeDepth)
+	for len(state.locals) > 0 && state.locals[len(state.locals)-1].depth > state.scopeDepth {
+		state.locals = state.locals[:len(state.locals)-1]
+	}
+}
+
+// discardLocals emits the code that pops the locals deeper than depth off
+// the stack, without forgetting them at compile time.
+func (c *Compiler) discardLocals(depth int) {
+	locals := c.current.locals
+	for k := len(locals) - 1; k >= 0 && locals[k].depth > depth; k-- {
+		if locals[k].captured {
+			c.emit(OP_CLOSE_UPVALUE)
+		} else {
+			c.emit(OP_POP)
+		}
+	}
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return c.current.function.Chunk
+}
+
+func (c *Compiler) identifierConstant(name string) int {
+	return c.makeConstant(name)
+}
+
+func (c *Compiler) makeConstant(value any) int {
+	constant := c.chunk().AddConstant(value)
+	if constant >= maxConstants {
+		panic(NewCompileError(c.token, "Too many constants in one chunk."))
+	}
+	return constant
+}
+
+func (c *Compiler) emit(op OpCode) {
+	c.chunk().Write(byte(op), c.token)
+}
+
+func (c *Compiler) emitByte(op OpCode, operand int) {
+	c.emit(op)
+	c.chunk().Write(byte(operand), c.token)
+}
+
+func (c *Compiler) emitShort(op OpCode, operand int) {
+	c.emit(op)
+	c.chunk().Write(byte(operand>>8), c.token)
+	c.chunk().Write(byte(operand), c.token)
+}
+
+func (c *Compiler) emitReturn() {
+	if c.current.kind == FN_INITIALIZER {
+		c.emitByte(OP_GET_LOCAL, 0)
+	} else {
+		c.emit(OP_NIL)
+	}
+	c.emit(OP_RETURN)
+}
+
+// emitJump emits a forward jump and returns the offset of its operand,
+// to be filled in by patchJump.
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitShort(op, 0xffff)
+	return len(c.chunk().Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int) {
+	jump := len(c.chunk().Code) - offset - 2
+	if jump > maxJump {
+		// Report at the statement that emitted the jump.
+		panic(NewCompileError(c.chunk().Tokens[offset-1], "Too much code to jump over."))
+	}
+	c.chunk().Code[offset] = byte(jump >> 8)
+	c.chunk().Code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	jump := len(c.chunk().Code) - loopStart + 3
+	if jump > maxJump {
+		panic(NewCompileError(c.token, "Loop body too large."))
+	}
+	c.emitShort(OP_LOOP, jump)
+}
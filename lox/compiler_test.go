@@ -0,0 +1,56 @@
+package lox_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+// repeat joins n copies of format, each formatted with its index.
+func repeat(n int, format, sep string) string {
+	parts := make([]string, n)
+	for k := range parts {
+		parts[k] = fmt.Sprintf(format, k)
+	}
+	return strings.Join(parts, sep)
+}
+
+func TestCompileLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+		want   string
+	}{
+		{"locals", "{\n" + repeat(256, "var a%d;", "\n") + "\n}", 257,
+			"Too many local variables in function."},
+		{"jump", "var x = 0;\n\nif (x == 0) {\n" + strings.Repeat("x = x + 1;\n", 10000) + "}", 3,
+			"Too much code to jump over."},
+		{"list", "var xs = [" + strings.Repeat("nil, ", 65535) + "nil];", 1,
+			"Too many elements in list literal."},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := newLox(lox.EngineVM, &strings.Builder{}).Run(test.source)
+			var compileError *lox.CompileError
+			if !errors.As(err, &compileError) {
+				t.Fatalf("expected a compile error but got %v", err)
+			}
+			if compileError.Message != test.want || compileError.Token.Line != test.line {
+				t.Errorf("got %q at line %d, want %q at line %d",
+					compileError.Message, compileError.Token.Line, test.want, test.line)
+			}
+			if got := lox.ExitCode(err); got != 65 {
+				t.Errorf("got exit code %d, want 65", got)
+			}
+
+			// The interpreter has no such limits.
+			if err := newLox(lox.EngineInterpreter, &strings.Builder{}).Run(test.source); err != nil {
+				t.Errorf("interpreter: %v", err)
+			}
+		})
+	}
+}
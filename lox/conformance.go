@@ -0,0 +1,229 @@
+package lox
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The annotations understood by the conformance runner. They follow the
+// conventions of the Crafting Interpreters test suite, where lines marked
+// for the C implementation only are ignored.
+var (
+	expectOutput       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeError = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectLineError    = regexp.MustCompile(`// \[(?:java )?line (\d+)\] (Error.*)`)
+	expectError        = regexp.MustCompile(`// (Error.*)`)
+	cOnlyError         = regexp.MustCompile(`// \[c line \d+\]`)
+)
+
+// expectedError is a compile or runtime error a test expects.
+type expectedError struct {
+	line    int
+	message string
+}
+
+func (e expectedError) String() string {
+	return fmt.Sprintf("[line %d] %s", e.line, e.message)
+}
+
+// conformanceTest holds the expectations parsed from a test script.
+type conformanceTest struct {
+	path         string
+	output       []string
+	errors       []expectedError
+	runtimeError *expectedError
+	exitCode     int
+}
+
+func parseConformanceTest(path string) (*conformanceTest, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	test := &conformanceTest{path: path}
+	scanner := bufio.NewScanner(bytes.NewReader(source))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+
+		if match := expectOutput.FindStringSubmatch(text); match != nil {
+			test.output = append(test.output, match[1])
+		} else if match := expectRuntimeError.FindStringSubmatch(text); match != nil {
+			test.runtimeError = &expectedError{line, match[1]}
+			test.exitCode = 70
+		} else if cOnlyError.MatchString(text) {
+			continue
+		} else if match := expectLineError.FindStringSubmatch(text); match != nil {
+			errorLine, _ := strconv.Atoi(match[1])
+			test.errors = append(test.errors,
+				expectedError{errorLine, errorMessage(match[2])})
+			test.exitCode = 65
+		} else if match := expectError.FindStringSubmatch(text); match != nil {
+			test.errors = append(test.errors,
+				expectedError{line, errorMessage(match[1])})
+			test.exitCode = 65
+		}
+	}
+	return test, scanner.Err()
+}
+
+// errorMessage strips the location from an annotation such as
+// "Error at 'x': message", since locations are reported differently.
+func errorMessage(annotation string) string {
+	if _, message, ok := strings.Cut(annotation, ": "); ok {
+		return message
+	}
+	return annotation
+}
+
+// run executes the test in a fresh engine and returns the mismatches.
+func (t *conformanceTest) run(options ...Option) []string {
+	var stdout, stderr bytes.Buffer
+	options = append(options, WithStdout(&stdout), WithStderr(&stderr))
+	err := New(options...).RunFile(t.path)
+
+	var failures []string
+
+	output := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if stdout.Len() == 0 {
+		output = nil
+	}
+	for k := 0; k < len(output) || k < len(t.output); k++ {
+		switch {
+		case k >= len(t.output):
+			failures = append(failures, fmt.Sprintf("Unexpected output '%s'.", output[k]))
+		case k >= len(output):
+			failures = append(failures, fmt.Sprintf("Missing expected output '%s'.", t.output[k]))
+		case output[k] != t.output[k]:
+			failures = append(failures, fmt.Sprintf(
+				"Expected output '%s' but got '%s'.", t.output[k], output[k]))
+		}
+	}
+
+	var runtimeError *RuntimeError
+	errors.As(err, &runtimeError)
+	compileErrors := reportedCompileErrors(err)
+
+	switch {
+	case t.runtimeError != nil && runtimeError == nil:
+		failures = append(failures, fmt.Sprintf(
+			"Expected runtime error '%s' but got none.", t.runtimeError))
+	case t.runtimeError != nil:
+		got := expectedError{runtimeError.Token.Line, runtimeError.Message}
+		if got != *t.runtimeError {
+			failures = append(failures, fmt.Sprintf(
+				"Expected runtime error '%s' but got '%s'.", t.runtimeError, got))
+		}
+	case runtimeError != nil:
+		failures = append(failures, fmt.Sprintf(
+			"Unexpected runtime error '%s'.", runtimeError.Error()))
+	}
+
+	for _, expected := range t.errors {
+		found := false
+		for k, got := range compileErrors {
+			if got == expected {
+				compileErrors = append(compileErrors[:k], compileErrors[k+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("Missing expected error '%s'.", expected))
+		}
+	}
+	for _, got := range compileErrors {
+		failures = append(failures, fmt.Sprintf("Unexpected error '%s'.", got))
+	}
+
+	if err == nil && stderr.Len() > 0 {
+		failures = append(failures, fmt.Sprintf(
+			"Unexpected output on stderr '%s'.", strings.TrimSpace(stderr.String())))
+	}
+
+	if code := ExitCode(err); code != t.exitCode {
+		failures = append(failures, fmt.Sprintf(
+			"Expected exit code %d but got %d.", t.exitCode, code))
+	}
+
+	return failures
+}
+
+// reportedCompileErrors lists the scan, parse and resolve errors in err.
+func reportedCompileErrors(err error) []expectedError {
+	var reported []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		reported = joined.Unwrap()
+	} else if err != nil {
+		reported = []error{err}
+	}
+
+	var compileErrors []expectedError
+	for _, err := range reported {
+		switch err := err.(type) {
+		case *ScanError:
+			compileErrors = append(compileErrors, expectedError{err.Line, err.Message})
+		case *ParseError:
+			compileErrors = append(compileErrors, expectedError{err.Token.Line, err.Message})
+		case *ResolveError:
+			compileErrors = append(compileErrors, expectedError{err.Token.Line, err.Message})
+		case *CompileError:
+			compileErrors = append(compileErrors, expectedError{err.Token.Line, err.Message})
+		}
+	}
+	return compileErrors
+}
+
+// RunConformanceTests runs every .lox file under dir, compares its output,
+// errors and exit code with the annotations in the file and writes a
+// PASS or FAIL line per file to out. Options configure the engine every
+// test runs in.
+func RunConformanceTests(dir string, out io.Writer, options ...Option) (passed, failed int, err error) {
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Names starting with an underscore hold helpers, such as imported
+		// modules, that aren't tests of their own.
+		if path != dir && strings.HasPrefix(entry.Name(), "_") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".lox" {
+			return nil
+		}
+
+		test, err := parseConformanceTest(path)
+		if err != nil {
+			return err
+		}
+
+		failures := test.run(options...)
+		if len(failures) == 0 {
+			passed++
+			fmt.Fprintf(out, "PASS %s\n", path)
+			return nil
+		}
+
+		failed++
+		fmt.Fprintf(out, "FAIL %s\n", path)
+		for _, failure := range failures {
+			fmt.Fprintf(out, "     %s\n", failure)
+		}
+		return nil
+	})
+
+	fmt.Fprintf(out, "\n%d passed, %d failed.\n", passed, failed)
+	return passed, failed, err
+}
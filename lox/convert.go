@@ -0,0 +1,305 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// loxPackage is the import path of this package. Pointers to its types are
+// already Lox values and are never wrapped as host objects.
+var loxPackage = reflect.TypeOf(Instance{}).PkgPath()
+
+// ToLox converts a Go value to the equivalent Lox value: numbers become
+// float64, slices and arrays lists, maps Lox maps and struct pointers
+// host objects. Values without an equivalent are wrapped in a GoValue.
+func ToLox(value any) any {
+	return fromGo(reflect.ValueOf(value))
+}
+
+func convertArguments(ftype reflect.Type, arguments []any) ([]reflect.Value, error) {
+	numIn := ftype.NumIn()
+	if ftype.IsVariadic() && len(arguments) < numIn-1 {
+		return nil, fmt.Errorf("Expected at least %d arguments but got %d.",
+			numIn-1, len(arguments))
+	}
+
+	args := make([]reflect.Value, len(arguments))
+	for k, argument := range arguments {
+		var ptype reflect.Type
+		if ftype.IsVariadic() && k >= numIn-1 {
+			ptype = ftype.In(numIn - 1).Elem()
+		} else {
+			ptype = ftype.In(k)
+		}
+
+		arg, err := toGo(argument, ptype)
+		if err != nil {
+			return nil, fmt.Errorf("Argument %d: %s", k+1, err)
+		}
+		args[k] = arg
+	}
+	return args, nil
+}
+
+// ToGo converts a Lox value to a plain Go value. Host objects and Go values
+// are unwrapped to the values they hold, lists become []any and maps
+// map[any]any; other values are returned as they are.
+func ToGo(value any) any {
+	switch value := value.(type) {
+	case *HostObject:
+		return value.Value()
+	case *GoValue:
+		return value.Value()
+	case *LoxList:
+		elements := make([]any, len(value.Elements))
+		for k, element := range value.Elements {
+			elements[k] = ToGo(element)
+		}
+		return elements
+	case *LoxMap:
+		entries := make(map[any]any, value.Len())
+		for _, key := range value.keys {
+			entries[ToGo(key)] = ToGo(value.entries[key])
+		}
+		return entries
+	default:
+		return value
+	}
+}
+
+// toGo converts a Lox value to a Go value of type t.
+func toGo(value any, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("Expected %s but got nil.", t)
+	}
+
+	if host, ok := value.(*HostObject); ok {
+		if host.value.Type().AssignableTo(t) {
+			return host.value, nil
+		}
+		if host.value.Elem().Type().AssignableTo(t) {
+			return host.value.Elem(), nil
+		}
+	}
+	if handle, ok := value.(*GoValue); ok && handle.value.Type().AssignableTo(t) {
+		return handle.value, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if number, ok := value.(float64); ok {
+			if reflect.Zero(t).OverflowFloat(number) {
+				return reflect.Value{}, fmt.Errorf("Expected %s but got %v.", t, number)
+			}
+			return reflect.ValueOf(number).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if number, ok := value.(float64); ok {
+			if number != math.Trunc(number) {
+				return reflect.Value{}, fmt.Errorf("Expected an integer but got %v.", number)
+			}
+			// Values out of range would wrap around when narrowed.
+			if number < math.MinInt64 || number >= math.MaxInt64 ||
+				reflect.Zero(t).OverflowInt(int64(number)) {
+				return reflect.Value{}, fmt.Errorf("Expected %s but got %v.", t, number)
+			}
+			return reflect.ValueOf(int64(number)).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number, ok := value.(float64); ok {
+			if number != math.Trunc(number) || number < 0 {
+				return reflect.Value{}, fmt.Errorf("Expected a non-negative integer but got %v.", number)
+			}
+			if number >= math.MaxUint64 || reflect.Zero(t).OverflowUint(uint64(number)) {
+				return reflect.Value{}, fmt.Errorf("Expected %s but got %v.", t, number)
+			}
+			return reflect.ValueOf(uint64(number)).Convert(t), nil
+		}
+	case reflect.String:
+		if text, ok := value.(string); ok {
+			return reflect.ValueOf(text).Convert(t), nil
+		}
+	case reflect.Bool:
+		if boolean, ok := value.(bool); ok {
+			return reflect.ValueOf(boolean).Convert(t), nil
+		}
+	case reflect.Slice:
+		if list, ok := value.(*LoxList); ok {
+			slice := reflect.MakeSlice(t, len(list.Elements), len(list.Elements))
+			for k, element := range list.Elements {
+				converted, err := toGo(element, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				slice.Index(k).Set(converted)
+			}
+			return slice, nil
+		}
+	case reflect.Map:
+		if m, ok := value.(*LoxMap); ok {
+			result := reflect.MakeMapWithSize(t, m.Len())
+			for _, key := range m.keys {
+				entry := m.entries[key]
+				convertedKey, err := toGo(key, t.Key())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				convertedEntry, err := toGo(entry, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				result.SetMapIndex(convertedKey, convertedEntry)
+			}
+			return result, nil
+		}
+	case reflect.Interface:
+		if v := reflect.ValueOf(ToGo(value)); v.Type().AssignableTo(t) {
+			return v, nil
+		}
+	default:
+		if v := reflect.ValueOf(value); v.Type().AssignableTo(t) {
+			return v, nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("Expected %s but got %s.", t, typeName(value))
+}
+
+// fromGo converts a Go value to a Lox value.
+func fromGo(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return fromGo(v.Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			if v.Elem().Type().PkgPath() == loxPackage {
+				return v.Interface()
+			}
+			return &HostObject{v}
+		}
+	case reflect.Struct:
+		// Addressable structs, such as fields of host objects and elements
+		// of slices, are shared so that writes reach the Go value.
+		if v.CanAddr() {
+			return fromGo(v.Addr())
+		}
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return fromGo(ptr)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		elements := make([]any, v.Len())
+		for k := range elements {
+			elements[k] = fromGo(v.Index(k))
+		}
+		return NewLoxList(elements)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		keys := make([]any, 0, v.Len())
+		entries := make(map[any]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fromGo(iter.Key())
+			keys = append(keys, key)
+			entries[key] = fromGo(iter.Value())
+		}
+		slices.SortFunc(keys, compareKeys)
+		m := NewLoxMap()
+		for _, key := range keys {
+			if m.Put(key, entries[key]) != nil {
+				return &GoValue{v}
+			}
+		}
+		return m
+	case reflect.Func:
+		if v.IsNil() {
+			return nil
+		}
+		native, err := NewGoFunc("func", v.Interface())
+		if err == nil {
+			return native
+		}
+	}
+
+	return &GoValue{v}
+}
+
+// fieldCopy marks the lists and maps converted from a Go field read-only,
+// since changes to them wouldn't reach the field.
+func fieldCopy(value any) any {
+	switch value := value.(type) {
+	case *LoxList:
+		value.readOnly = true
+		for _, element := range value.Elements {
+			fieldCopy(element)
+		}
+	case *LoxMap:
+		value.readOnly = true
+		for _, entry := range value.entries {
+			fieldCopy(entry)
+		}
+	}
+	return value
+}
+
+// typeName describes the type of a Lox value in error messages.
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case *Instance:
+		return "instance"
+	case *LoxClass:
+		return "class"
+	case *LoxList:
+		return "list"
+	case *LoxMap:
+		return "map"
+	case *HostObject:
+		return value.(*HostObject).typeName()
+	case *GoValue:
+		return value.(*GoValue).value.Type().String()
+	case Callable:
+		return "function"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,65 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Call invokes the global Lox function called name. Arguments are
+// converted with ToLox and the result with ToGo. Runtime errors raised by
+// the function are returned as *RuntimeError.
+func (i *Interpreter) Call(name string, args ...any) (any, error) {
+	value, ok := i.lookupGlobal(name)
+	if !ok {
+		return nil, fmt.Errorf("Undefined function '%s'.", name)
+	}
+
+	function, ok := value.(Callable)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a function.", name)
+	}
+
+	arguments := make([]any, len(args))
+	for k, arg := range args {
+		arguments[k] = ToLox(arg)
+	}
+
+	if message := arityError(function, len(arguments)); message != "" {
+		return nil, errors.New(message)
+	}
+
+	exit := i.enter(i.lox.context)
+	defer exit()
+	defer i.closeAbandoned()
+
+	var result any
+	err := i.protect(func() {
+		i.pushFrame(function, i.callSite())
+		result = function.Call(i, arguments)
+		i.popFrame()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ToGo(result), nil
+}
+
+// Global returns the value of the global Lox variable called name,
+// converted with ToGo.
+func (i *Interpreter) Global(name string) (any, bool) {
+	value, ok := i.lookupGlobal(name)
+	if !ok {
+		return nil, false
+	}
+	return ToGo(value), true
+}
+
+// lookupGlobal finds a global of the main script or a value defined by the
+// embedder.
+func (i *Interpreter) lookupGlobal(name string) (any, bool) {
+	if value, ok := i.globals.Values[name]; ok {
+		return value, true
+	}
+	value, ok := i.builtins.Values[name]
+	return value, ok
+}
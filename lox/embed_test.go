@@ -0,0 +1,93 @@
+package lox_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+func TestCall(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		l := newLox(engine, &strings.Builder{})
+		err := l.Run(`
+fun describe(name, tags) {
+  return {"name": name, "count": tags.len(), "first": tags[0]};
+}
+fun fail(x) {
+  return x.missing;
+}
+var port = 8080;
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := l.Interpreter()
+
+		result, err := i.Call("describe", "Ann", []string{"a", "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[any]any{"name": "Ann", "count": 2.0, "first": "a"}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("got %v, want %v", result, want)
+		}
+
+		if _, err := i.Call("describe", "Ann"); err == nil ||
+			err.Error() != "Expected 2 arguments but got 1." {
+			t.Errorf("got %v, want an arity error", err)
+		}
+		if _, err := i.Call("nothing"); err == nil ||
+			err.Error() != "Undefined function 'nothing'." {
+			t.Errorf("got %v, want an undefined function error", err)
+		}
+		if _, err := i.Call("port"); err == nil ||
+			err.Error() != "'port' is not a function." {
+			t.Errorf("got %v, want a not a function error", err)
+		}
+
+		_, err = i.Call("fail", 1)
+		var runtimeError *lox.RuntimeError
+		if !errors.As(err, &runtimeError) {
+			t.Fatalf("got %v, want a runtime error", err)
+		}
+		if got, want := runtimeError.Message, "Only instances have properties."; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		// The engine still works after an error.
+		result, err = i.Call("describe", "Bob", []any{true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := result.(map[any]any)["first"]; got != true {
+			t.Errorf("got %v, want true", got)
+		}
+	})
+}
+
+func TestGlobal(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		l := newLox(engine, &strings.Builder{})
+		l.Interpreter().Define("limit", 10)
+		if err := l.Run(`var port = 8080; var hosts = ["a", "b"];`); err != nil {
+			t.Fatal(err)
+		}
+		i := l.Interpreter()
+
+		if port, ok := i.Global("port"); !ok || port != 8080.0 {
+			t.Errorf("got %v, %v, want 8080", port, ok)
+		}
+		if hosts, _ := i.Global("hosts"); !reflect.DeepEqual(hosts, []any{"a", "b"}) {
+			t.Errorf("got %v, want [a b]", hosts)
+		}
+		if limit, ok := i.Global("limit"); !ok || limit != 10.0 {
+			t.Errorf("got %v, %v, want 10", limit, ok)
+		}
+		if _, ok := i.Global("missing"); ok {
+			t.Error("got a value for an undefined global")
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package lox
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Engine selects how scripts are executed. Both engines share the same
+// front end, globals and runtime values.
+type Engine string
+
+const (
+	EngineInterpreter Engine = "tree"
+	EngineVM          Engine = "vm"
+)
+
+// Engines lists the available execution engines.
+var Engines = []Engine{EngineInterpreter, EngineVM}
+
+// ParseEngine validates the name of an execution engine.
+func ParseEngine(name string) (Engine, error) {
+	engine := Engine(name)
+	if !slices.Contains(Engines, engine) {
+		return "", fmt.Errorf("unknown engine '%s'", name)
+	}
+	return engine, nil
+}
+
+// WithEngine selects the execution engine. The tree-walking interpreter
+// is the default.
+func WithEngine(engine Engine) Option {
+	return func(l *Lox) {
+		l.engine = engine
+	}
+}
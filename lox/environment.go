@@ -19,7 +19,7 @@ func (e *Environment) Get(name *Token) any {
 		return e.Enclosing.Get(name)
 	} else {
 		panic(NewRuntimeError(
-			name, fmt.Sprintf("Undefined variable: '%s'.", name.Lexeme),
+			name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme),
 		))
 	}
 }
@@ -32,7 +32,7 @@ func (e *Environment) Assign(name *Token, value any) {
 		e.Enclosing.Assign(name, value)
 	} else {
 		panic(NewRuntimeError(
-			name, fmt.Sprintf("Undefined variable: '%s'.", name.Lexeme),
+			name, fmt.Sprintf("Undefined variable '%s'.", name.Lexeme),
 		))
 	}
 }
@@ -3,21 +3,22 @@ package lox
 import "fmt"
 
 type ScanError struct {
-	line    int
-	message string
+	Line    int
+	Column  int
+	Message string
 }
 
-func NewScanError(line int, message string) *ScanError {
-	return &ScanError{line, message}
+func NewScanError(line, column int, message string) *ScanError {
+	return &ScanError{line, column, message}
 }
 
 func (s *ScanError) Error() string {
-	return fmt.Sprintf("[line %d]: Scan Error: %s", s.line, s.message)
+	return fmt.Sprintf("[line %d, column %d]: Scan Error: %s", s.Line, s.Column, s.Message)
 }
 
 type ParseError struct {
-	token   *Token
-	message string
+	Token   *Token
+	Message string
 }
 
 func NewParseError(token *Token, message string) *ParseError {
@@ -26,38 +27,49 @@ func NewParseError(token *Token, message string) *ParseError {
 
 func (p *ParseError) Error() string {
 	var where string
-	if p.token.Type == EOF {
+	if p.Token.Type == EOF {
 		where = "EOF"
 	} else {
-		where = p.token.Lexeme
+		where = p.Token.Lexeme
 	}
 	return fmt.Sprintf("[line %d] at %s: Parse Error: %s",
-		p.token.Line, where, p.message)
+		p.Token.Line, where, p.Message)
 }
 
+// RuntimeError is raised by a failing operation or by a throw statement,
+// in which case Value holds the thrown value. Fatal errors, such as
+// exceeded execution limits, can't be caught by scripts.
 type RuntimeError struct {
-	token   *Token
-	message string
+	Token   *Token
+	Message string
+	Frames  []CallFrame
+	Value   any
+	Fatal   bool
 }
 
 func NewRuntimeError(token *Token, message string) *RuntimeError {
-	return &RuntimeError{token, message}
+	return &RuntimeError{Token: token, Message: message}
 }
 
 func (r *RuntimeError) Error() string {
 	var where string
-	if r.token.Type == EOF {
+	if r.Token.Type == EOF {
 		where = "EOF"
 	} else {
-		where = r.token.Lexeme
+		where = r.Token.Lexeme
 	}
 	return fmt.Sprintf("[line %d] at %s: Runtime Error: %s",
-		r.token.Line, where, r.message)
+		r.Token.Line, where, r.Message)
+}
+
+// Traceback formats the Lox call stack captured when the error escaped.
+func (r *RuntimeError) Traceback() string {
+	return formatTraceback(r.Frames)
 }
 
 type ResolveError struct {
-	token   *Token
-	message string
+	Token   *Token
+	Message string
 }
 
 func NewResolveError(token *Token, message string) *ResolveError {
@@ -66,11 +78,31 @@ func NewResolveError(token *Token, message string) *ResolveError {
 
 func (r *ResolveError) Error() string {
 	var where string
-	if r.token.Type == EOF {
+	if r.Token.Type == EOF {
 		where = "EOF"
 	} else {
-		where = r.token.Lexeme
+		where = r.Token.Lexeme
 	}
 	return fmt.Sprintf("[line %d] at %s: Resolve Error: %s",
-		r.token.Line, where, r.message)
+		r.Token.Line, where, r.Message)
+}
+
+type CompileError struct {
+	Token   *Token
+	Message string
+}
+
+func NewCompileError(token *Token, message string) *CompileError {
+	return &CompileError{token, message}
+}
+
+func (c *CompileError) Error() string {
+	var where string
+	if c.Token.Type == EOF {
+		where = "EOF"
+	} else {
+		where = c.Token.Lexeme
+	}
+	return fmt.Sprintf("[line %d] at %s: Compile Error: %s",
+		c.Token.Line, where, c.Message)
 }
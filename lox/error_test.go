@@ -0,0 +1,78 @@
+package lox_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+// runError runs source and returns the runtime error it raises.
+func runError(t *testing.T, l *lox.Lox, source string) *lox.RuntimeError {
+	t.Helper()
+	var runtimeError *lox.RuntimeError
+	if err := l.Run(source); !errors.As(err, &runtimeError) {
+		t.Fatalf("expected a runtime error but got %v", err)
+	}
+	return runtimeError
+}
+
+func TestTraceback(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		l := newLox(engine, &strings.Builder{})
+		err := runError(t, l, `
+class Parser {
+  parse(text) {
+    return check(text);
+  }
+}
+fun check(text) {
+  return text.length;
+}
+Parser().parse("x");
+`)
+
+		want := []lox.CallFrame{
+			{Function: "check", Line: 8},
+			{Function: "parse", Class: "Parser", Line: 4},
+			{Function: "script", Line: 10},
+		}
+		if !reflect.DeepEqual(err.Frames, want) {
+			t.Errorf("got frames %v, want %v", err.Frames, want)
+		}
+		wantText := "  in check (line 8)\n" +
+			"  called from Parser.parse (line 4)\n" +
+			"  called from script (line 10)"
+		if got := err.Traceback(); got != wantText {
+			t.Errorf("got traceback %q, want %q", got, wantText)
+		}
+	})
+}
+
+func TestTracebackRepeatedFrames(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		l := newLox(engine, &strings.Builder{})
+		err := runError(t, l, `
+fun countdown(n) {
+  if (n == 0) return nil.value;
+  return countdown(n - 1);
+}
+countdown(10);
+`)
+
+		if got, want := len(err.Frames), 12; got != want {
+			t.Errorf("got %d frames, want %d", got, want)
+		}
+		want := "  in countdown (line 3)\n" +
+			"  called from countdown (line 4)\n" +
+			"  called from countdown (line 4)\n" +
+			"  called from countdown (line 4)\n" +
+			"  [previous frame repeated 7 more times]\n" +
+			"  called from script (line 6)"
+		if got := err.Traceback(); got != want {
+			t.Errorf("got traceback %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package lox
+
+import "fmt"
+
+// LoxError is the value a catch clause receives for a runtime error. Scripts
+// create their own with the Error native; its line and trace are filled in
+// when it is thrown.
+type LoxError struct {
+	Message string
+	err     *RuntimeError
+}
+
+func NewLoxError(message string) *LoxError {
+	return &LoxError{Message: message}
+}
+
+func (e *LoxError) Get(name *Token) any {
+	switch name.Lexeme {
+	case "message":
+		return e.Message
+	case "line":
+		if e.err == nil {
+			return nil
+		}
+		return float64(e.err.Token.Line)
+	case "trace":
+		if e.err == nil {
+			return nil
+		}
+		return e.err.Traceback()
+	}
+
+	panic(NewRuntimeError(name,
+		fmt.Sprintf("Undefined property '%s'.", name.Lexeme)))
+}
+
+func (e *LoxError) Set(name *Token, value any) {
+	panic(NewRuntimeError(name, "Only instances have fields."))
+}
+
+func (e *LoxError) String() string {
+	return "Error: " + e.Message
+}
+
+// thrown returns the runtime error that carries value from a throw
+// statement to the nearest catch clause. Rethrowing a caught error keeps
+// its original location and trace.
+func (i *Interpreter) thrown(keyword *Token, value any) *RuntimeError {
+	if e, ok := value.(*LoxError); ok {
+		if e.err == nil {
+			e.err = NewRuntimeError(keyword, e.Message)
+			e.err.Value = e
+		}
+		return e.err
+	}
+
+	err := NewRuntimeError(keyword, i.stringify(value, keyword))
+	err.Value = value
+	return err
+}
+
+// caught returns the value a catch clause binds for err.
+func caught(err *RuntimeError) any {
+	if err.Value == nil {
+		err.Value = &LoxError{err.Message, err}
+	}
+	return err.Value
+}
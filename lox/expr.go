@@ -4,11 +4,19 @@ type ExprVisitor interface {
 	VisitAssignExpr(expr *Assign) any
 	VisitBinaryExpr(expr *Binary) any
 	VisitCallExpr(expr *Call) any
+	VisitCompoundExpr(expr *Compound) any
+	VisitConditionalExpr(expr *Conditional) any
 	VisitGetExpr(expr *Get) any
 	VisitGroupingExpr(expr *Grouping) any
+	VisitIndexExpr(expr *Index) any
+	VisitLambdaExpr(expr *Lambda) any
+	VisitListExpr(expr *List) any
 	VisitLiteralExpr(expr *Literal) any
 	VisitLogicalExpr(expr *Logical) any
+	VisitMapExpr(expr *Map) any
 	VisitSetExpr(expr *Set) any
+	VisitStringifyExpr(expr *Stringify) any
+	VisitSetIndexExpr(expr *SetIndex) any
 	VisitSuperExpr(expr *Super) any
 	VisitThisExpr(expr *This) any
 	VisitUnaryExpr(expr *Unary) any
@@ -50,16 +58,47 @@ type Call struct {
 	Callee Expr
 	Paren *Token
 	Arguments []Expr
+	Names []*Token
 }
 
-func NewCall(callee Expr, paren *Token, arguments []Expr, ) Expr {
-	return &Call{ callee, paren, arguments,  }
+func NewCall(callee Expr, paren *Token, arguments []Expr, names []*Token, ) Expr {
+	return &Call{ callee, paren, arguments, names,  }
 }
 
 func (c *Call) Accept(ev ExprVisitor) any {
 	return ev.VisitCallExpr(c)
 }
 
+type Compound struct {
+	Target Expr
+	Operator *Token
+	Value Expr
+	Postfix bool
+}
+
+func NewCompound(target Expr, operator *Token, value Expr, postfix bool, ) Expr {
+	return &Compound{ target, operator, value, postfix,  }
+}
+
+func (c *Compound) Accept(ev ExprVisitor) any {
+	return ev.VisitCompoundExpr(c)
+}
+
+type Conditional struct {
+	Condition Expr
+	Question *Token
+	ThenBranch Expr
+	ElseBranch Expr
+}
+
+func NewConditional(condition Expr, question *Token, thenBranch Expr, elseBranch Expr, ) Expr {
+	return &Conditional{ condition, question, thenBranch, elseBranch,  }
+}
+
+func (c *Conditional) Accept(ev ExprVisitor) any {
+	return ev.VisitConditionalExpr(c)
+}
+
 type Get struct {
 	Object Expr
 	Name *Token
@@ -85,6 +124,46 @@ func (g *Grouping) Accept(ev ExprVisitor) any {
 	return ev.VisitGroupingExpr(g)
 }
 
+type Index struct {
+	Object Expr
+	Bracket *Token
+	Index Expr
+}
+
+func NewIndex(object Expr, bracket *Token, index Expr, ) Expr {
+	return &Index{ object, bracket, index,  }
+}
+
+func (i *Index) Accept(ev ExprVisitor) any {
+	return ev.VisitIndexExpr(i)
+}
+
+type Lambda struct {
+	Keyword *Token
+	Function *Function
+}
+
+func NewLambda(keyword *Token, function *Function, ) Expr {
+	return &Lambda{ keyword, function,  }
+}
+
+func (l *Lambda) Accept(ev ExprVisitor) any {
+	return ev.VisitLambdaExpr(l)
+}
+
+type List struct {
+	Bracket *Token
+	Elements []Expr
+}
+
+func NewList(bracket *Token, elements []Expr, ) Expr {
+	return &List{ bracket, elements,  }
+}
+
+func (l *List) Accept(ev ExprVisitor) any {
+	return ev.VisitListExpr(l)
+}
+
 type Literal struct {
 	Value any
 }
@@ -111,6 +190,20 @@ func (l *Logical) Accept(ev ExprVisitor) any {
 	return ev.VisitLogicalExpr(l)
 }
 
+type Map struct {
+	Brace *Token
+	Keys []Expr
+	Values []Expr
+}
+
+func NewMap(brace *Token, keys []Expr, values []Expr, ) Expr {
+	return &Map{ brace, keys, values,  }
+}
+
+func (m *Map) Accept(ev ExprVisitor) any {
+	return ev.VisitMapExpr(m)
+}
+
 type Set struct {
 	Object Expr
 	Name *Token
@@ -125,6 +218,34 @@ func (s *Set) Accept(ev ExprVisitor) any {
 	return ev.VisitSetExpr(s)
 }
 
+type Stringify struct {
+	Token *Token
+	Expression Expr
+}
+
+func NewStringify(token *Token, expression Expr, ) Expr {
+	return &Stringify{ token, expression,  }
+}
+
+func (s *Stringify) Accept(ev ExprVisitor) any {
+	return ev.VisitStringifyExpr(s)
+}
+
+type SetIndex struct {
+	Object Expr
+	Bracket *Token
+	Index Expr
+	Value Expr
+}
+
+func NewSetIndex(object Expr, bracket *Token, index Expr, value Expr, ) Expr {
+	return &SetIndex{ object, bracket, index, value,  }
+}
+
+func (s *SetIndex) Accept(ev ExprVisitor) any {
+	return ev.VisitSetIndexExpr(s)
+}
+
 type Super struct {
 	Keyword *Token
 	Method *Token
@@ -6,26 +6,55 @@ type LoxFunction struct {
 	Declaration   *Function
 	Closure       *Environment
 	IsInitializer bool
+	Class         *LoxClass
+	// Globals are the globals of the module the function was declared in.
+	Globals *Environment
 }
 
-func NewLoxFunction(declaration *Function, closure *Environment, isInitializer bool) *LoxFunction {
-	return &LoxFunction{declaration, closure, isInitializer}
+func NewLoxFunction(declaration *Function, closure, globals *Environment, isInitializer bool) *LoxFunction {
+	return &LoxFunction{declaration, closure, isInitializer, nil, globals}
 }
 
-func (f *LoxFunction) Arity() int {
-	return len(f.Declaration.Params)
+func (f *LoxFunction) Arity() (int, int) {
+	return functionArity(f.Declaration)
 }
 
-func (f *LoxFunction) Bind(instance *Instance) *LoxFunction {
+func (f *LoxFunction) Parameters() []string {
+	return parameterNames(f.Declaration)
+}
+
+func (f *LoxFunction) Bind(receiver any) Method {
 	environment := NewEnvironment(f.Closure)
-	environment.Define("this", instance)
-	return NewLoxFunction(f.Declaration, environment, f.IsInitializer)
+	environment.Define("this", receiver)
+	function := NewLoxFunction(f.Declaration, environment, f.Globals, f.IsInitializer)
+	function.Class = f.Class
+	return function
+}
+
+// IsGetter reports whether the function was declared without a parameter
+// list.
+func (f *LoxFunction) IsGetter() bool {
+	return f.Declaration.Params == nil
+}
+
+func (f *LoxFunction) Call(interpreter *Interpreter, arguments []any) any {
+	if f.Declaration.Generator {
+		return interpreter.newCoroutine(f, arguments)
+	}
+	return f.invoke(interpreter, arguments)
 }
 
-func (f *LoxFunction) Call(interpreter *Interpreter, arguments []any) (ret any) {
+// invoke runs the body of the function.
+func (f *LoxFunction) invoke(interpreter *Interpreter, arguments []any) (ret any) {
+	interpreter.checkCallDepth()
 	enclosing := interpreter.environment
+	globals := interpreter.globals
+	interpreter.globals = f.Globals
+	interpreter.depth++
 
 	defer func() {
+		interpreter.depth--
+		interpreter.globals = globals
 		if r := recover(); r != nil {
 			if val, ok := r.(*ReturnValue); ok {
 				if f.IsInitializer {
@@ -41,9 +70,25 @@ func (f *LoxFunction) Call(interpreter *Interpreter, arguments []any) (ret any)
 	}()
 
 	environment := NewEnvironment(f.Closure)
-	for i := 0; i < len(f.Declaration.Params); i++ {
-		environment.Define(f.Declaration.Params[i].Lexeme,
-			arguments[i])
+	declaration := f.Declaration
+	for k, param := range declaration.Params {
+		var value any
+		if k < len(arguments) && arguments[k] != missing {
+			value = arguments[k]
+		} else {
+			// Defaults see the parameters before them.
+			interpreter.environment = environment
+			value = interpreter.evaluate(declaration.Defaults[k])
+			interpreter.environment = enclosing
+		}
+		environment.Define(param.Lexeme, value)
+	}
+	if declaration.Rest != nil {
+		rest := make([]any, 0)
+		if len(arguments) > len(declaration.Params) {
+			rest = append(rest, arguments[len(declaration.Params):]...)
+		}
+		environment.Define(declaration.Rest.Lexeme, NewLoxList(rest))
 	}
 
 	interpreter.executeBlock(f.Declaration.Body, environment)
@@ -56,5 +101,36 @@ func (f *LoxFunction) Call(interpreter *Interpreter, arguments []any) (ret any)
 }
 
 func (f *LoxFunction) String() string {
-	return fmt.Sprintf("<fn %s>", f.Declaration.Name.Lexeme)
+	return fmt.Sprintf("<fn %s>", functionName(f.Declaration))
+}
+
+// functionName is the name a function is shown with in output and
+// tracebacks.
+func functionName(declaration *Function) string {
+	if declaration.Name == nil {
+		return "anonymous"
+	}
+	return declaration.Name.Lexeme
+}
+
+// functionArity returns the number of parameters of declaration without
+// a default and the number of all of them, or Variadic with a rest
+// parameter.
+func functionArity(declaration *Function) (int, int) {
+	required := 0
+	for required < len(declaration.Params) && declaration.Defaults[required] == nil {
+		required++
+	}
+	if declaration.Rest != nil {
+		return required, Variadic
+	}
+	return required, len(declaration.Params)
+}
+
+func parameterNames(declaration *Function) []string {
+	names := make([]string, len(declaration.Params))
+	for k, param := range declaration.Params {
+		names[k] = param.Lexeme
+	}
+	return names
 }
@@ -0,0 +1,274 @@
+package lox
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// LoxGenerator is the value of a call to a function containing yield. The
+// body runs up to the next yield on every call of the next method.
+type LoxGenerator struct {
+	*generator
+}
+
+type generator struct {
+	frame frame
+	body  generatorBody
+	// depth is the call depth of the body when it last yielded.
+	depth   int
+	done    bool
+	running bool
+	// closed is the number of times the engine was closed before the
+	// generator was created.
+	closed int
+}
+
+// generatorBody runs the body of a generator up to its next yield and
+// reports false once the body has finished. Each engine has its own.
+type generatorBody interface {
+	step(interpreter *Interpreter) (any, bool)
+}
+
+func newGenerator(i *Interpreter, function Callable, body generatorBody) *LoxGenerator {
+	return &LoxGenerator{&generator{
+		frame:  newFrame(function, nil),
+		body:   body,
+		closed: i.closed,
+	}}
+}
+
+// Get returns the built-in method name bound to the generator.
+func (g *LoxGenerator) Get(name *Token) any {
+	if name.Lexeme == "next" {
+		return NewNativeFunc("next", 0, func(i *Interpreter, args []any) (any, error) {
+			value, _ := g.resume(i, i.callSite())
+			return value, nil
+		})
+	}
+	panic(NewRuntimeError(name,
+		fmt.Sprintf("Undefined property '%s'.", name.Lexeme)))
+}
+
+func (g *LoxGenerator) Set(name *Token, value any) {
+	panic(NewRuntimeError(name, "Only instances have fields."))
+}
+
+func (g *LoxGenerator) String() string {
+	return fmt.Sprintf("<generator %s>", g.frame.function)
+}
+
+// resume runs the body up to its next yield and returns the yielded value,
+// or reports false once the body has finished. site is the call that
+// resumed the generator.
+func (g *generator) resume(i *Interpreter, site *Token) (any, bool) {
+	if g.running {
+		panic(NewRuntimeError(site, "Generator is already running."))
+	}
+	if g.done || g.closed != i.closed {
+		return nil, false
+	}
+	g.running = true
+	defer func() {
+		g.running = false
+	}()
+
+	// The body is done for good if it fails.
+	g.done = true
+	var value any
+	var ok bool
+	i.inGenerator(g, site, func() {
+		value, ok = g.body.step(i)
+	})
+	g.done = !ok
+	return value, ok
+}
+
+// inGenerator runs fn with the call stack of the generator g, which is
+// its own frame on top of the frames below. Every step of the body thus
+// sees the same stack, so state saved before a yield, such as the
+// handler of a try statement, is still valid after it. Steps nest, so
+// the frames below can share the array of the callers.
+func (i *Interpreter) inGenerator(g *generator, site *Token, fn func()) {
+	frames, depth := i.frames, i.depth
+	callers, callerDepth := i.callers, i.callerDepth
+	restore := func() {
+		i.frames, i.depth = frames, depth
+		i.callers, i.callerDepth = callers, callerDepth
+	}
+
+	top := g.frame
+	top.paren = site
+	i.callers = append(callers, frames...)
+	i.callerDepth += depth
+	i.frames = []frame{top}
+	i.depth = g.depth
+
+	defer func() {
+		if r := recover(); r != nil {
+			if err, ok := r.(*RuntimeError); ok && err.Frames == nil {
+				err.Frames = i.traceback(err.Token)
+			}
+			restore()
+			panic(r)
+		}
+	}()
+	i.checkCallDepth()
+	fn()
+	g.depth = i.depth
+	restore()
+}
+
+// generatorIterator drives a generator in a for-in loop.
+type generatorIterator struct {
+	generator *LoxGenerator
+	keyword   *Token
+}
+
+func (g *generatorIterator) Next(interpreter *Interpreter) (any, bool) {
+	return g.generator.resume(interpreter, g.keyword)
+}
+
+// coroutine is the body of a generator for the tree-walking interpreter.
+// It runs on a goroutine of its own, and control passes back and forth
+// over channels so that only one side runs at a time.
+type coroutine struct {
+	generator *generator
+	run       func()
+	started   bool
+	resumed   chan bool
+	yielded   chan coroutineStep
+}
+
+type coroutineStep struct {
+	value any
+	ok    bool
+	panic any
+}
+
+// newCoroutine creates the generator returned by a call of function. A
+// generator that is dropped while suspended is closed by a later call or
+// at the end of the run, so its goroutine doesn't live on.
+func (i *Interpreter) newCoroutine(function *LoxFunction, arguments []any) *LoxGenerator {
+	i.closeAbandoned()
+
+	c := &coroutine{
+		resumed: make(chan bool),
+		yielded: make(chan coroutineStep),
+	}
+	c.run = func() {
+		defer func() {
+			c.yielded <- coroutineStep{panic: recover()}
+		}()
+		// Start from the closure rather than the caller's environment, which
+		// would keep the generator itself from being collected.
+		i.environment = function.Closure
+		function.invoke(i, arguments)
+	}
+
+	g := newGenerator(i, function, c)
+	c.generator = g.generator
+	runtime.SetFinalizer(g, func(g *LoxGenerator) {
+		i.abandonedMutex.Lock()
+		i.abandoned = append(i.abandoned, g.generator)
+		i.abandonedMutex.Unlock()
+	})
+	return g
+}
+
+func (c *coroutine) step(i *Interpreter) (any, bool) {
+	step := c.transfer(i, true)
+	if step.panic != nil {
+		panic(step.panic)
+	}
+	return step.value, step.ok
+}
+
+// transfer runs the goroutine until it yields or finishes. Resuming it
+// with false closes it instead.
+func (c *coroutine) transfer(i *Interpreter, resume bool) coroutineStep {
+	environment, globals, current := i.environment, i.globals, i.coroutine
+	defer func() {
+		i.environment, i.globals, i.coroutine = environment, globals, current
+	}()
+
+	i.coroutine = c
+	if !c.started {
+		c.started = true
+		i.coroutines[c] = true
+		go c.run()
+	} else {
+		c.resumed <- resume
+	}
+	step := <-c.yielded
+	if !step.ok {
+		delete(i.coroutines, c)
+	}
+	return step
+}
+
+// yield hands value to the code that resumed the coroutine and waits to
+// be resumed again. It is called on the coroutine's goroutine.
+func (c *coroutine) yield(i *Interpreter, keyword *Token, value any) {
+	environment, globals := i.environment, i.globals
+	c.yielded <- coroutineStep{value: value, ok: true}
+	if !<-c.resumed {
+		// Unwind the goroutine without running any more Lox code.
+		err := NewRuntimeError(keyword, "Generator closed.")
+		err.Fatal = true
+		panic(err)
+	}
+	i.environment, i.globals = environment, globals
+}
+
+// closeAbandoned stops the goroutines of generators that were garbage
+// collected while suspended.
+func (i *Interpreter) closeAbandoned() {
+	i.abandonedMutex.Lock()
+	abandoned := i.abandoned
+	i.abandoned = nil
+	i.abandonedMutex.Unlock()
+
+	for _, g := range abandoned {
+		i.closeCoroutine(g.body.(*coroutine))
+	}
+}
+
+// close finishes all generators created so far, stopping the goroutines
+// of the suspended ones.
+func (i *Interpreter) close() {
+	i.closed++
+	for c := range i.coroutines {
+		i.closeCoroutine(c)
+	}
+}
+
+func (i *Interpreter) closeCoroutine(c *coroutine) {
+	g := c.generator
+	if !i.coroutines[c] || g.running {
+		return
+	}
+	g.done = true
+	i.inGenerator(g, NewToken(EOF, "", nil, 0), func() {
+		c.transfer(i, false)
+	})
+}
+
+// vmGenerator is the body of a generator for the bytecode VM. Its frames
+// live on a VM of their own, which keeps them between steps.
+type vmGenerator struct {
+	vm      *VM
+	started bool
+}
+
+func (g *vmGenerator) step(i *Interpreter) (any, bool) {
+	if !g.started {
+		g.started = true
+		i.depth++
+	}
+	value := g.vm.run(0)
+	if !g.vm.yielded {
+		return nil, false
+	}
+	g.vm.yielded = false
+	return value, true
+}
@@ -0,0 +1,94 @@
+package lox_test
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"lox/lox"
+)
+
+const naturals = `
+fun naturals() {
+  var n = 0;
+  while (true) {
+    try {
+      yield n;
+    } finally {
+      print "finally";
+    }
+    n = n + 1;
+  }
+}
+`
+
+// waitForGoroutines waits until at most want goroutines are left, since
+// closed generators take a moment to exit.
+func waitForGoroutines(t *testing.T, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d goroutines, want at most %d", runtime.NumGoroutine(), want)
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClose(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		before := runtime.NumGoroutine()
+		for range 200 {
+			var out strings.Builder
+			l := newLox(engine, &out)
+			if err := l.Run(naturals + `var g = naturals(); g.next();`); err != nil {
+				t.Fatal(err)
+			}
+			l.Close()
+
+			if err := l.Run(`print g.next();`); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != "nil\n" {
+				t.Fatalf("got output %q, want %q", got, "nil\n")
+			}
+		}
+		waitForGoroutines(t, before)
+	})
+}
+
+func TestAbandonedGenerators(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		if err := l.Run(naturals + `fun first() { return naturals().next(); }`); err != nil {
+			t.Fatal(err)
+		}
+
+		before := runtime.NumGoroutine()
+		for range 200 {
+			if _, err := l.Interpreter().Call("first"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Dropped generators are closed by the next run.
+		runtime.GC()
+		waitForFinalizers()
+		if err := l.Run(`print "done";`); err != nil {
+			t.Fatal(err)
+		}
+		waitForGoroutines(t, before)
+		if got := out.String(); got != "done\n" {
+			t.Errorf("got output %q, want %q", got, "done\n")
+		}
+	})
+}
+
+// waitForFinalizers gives the finalizers queued by a collection time to
+// run.
+func waitForFinalizers() {
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+}
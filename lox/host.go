@@ -0,0 +1,134 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HostObject exposes a Go struct to Lox. Exported fields can be read and
+// written as properties and exported methods are returned as bound
+// callables. Nested structs are shared, while slices and maps are read as
+// read-only copies. Lox names are matched against Go names as written or
+// with the first letter upper-cased, so both obj.name and obj.Name find
+// Name.
+type HostObject struct {
+	value reflect.Value
+}
+
+// NewHostObject wraps a pointer to a struct.
+func NewHostObject(ptr any) (*HostObject, error) {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("host object: expected a pointer to a struct but got %T", ptr)
+	}
+	if value.IsNil() {
+		return nil, fmt.Errorf("host object: nil %T", ptr)
+	}
+	return &HostObject{value}, nil
+}
+
+// Value returns the wrapped pointer.
+func (h *HostObject) Value() any {
+	return h.value.Interface()
+}
+
+func (h *HostObject) Get(name *Token) any {
+	for _, goName := range hostNames(name.Lexeme) {
+		if field, ok := h.field(goName); ok {
+			return fieldCopy(fromGo(field))
+		}
+
+		if method := h.value.MethodByName(goName); method.IsValid() {
+			native, err := NewGoFunc(h.typeName()+"."+goName, method.Interface())
+			if err != nil {
+				panic(NewRuntimeError(name, err.Error()))
+			}
+			return native
+		}
+	}
+
+	panic(NewRuntimeError(name,
+		fmt.Sprintf("Undefined property '%s'.", name.Lexeme)))
+}
+
+func (h *HostObject) Set(name *Token, value any) {
+	for _, goName := range hostNames(name.Lexeme) {
+		field, ok := h.field(goName)
+		if !ok {
+			continue
+		}
+		if !field.IsValid() {
+			panic(NewRuntimeError(name, fmt.Sprintf(
+				"Can't set field '%s' through a nil embedded struct.", name.Lexeme)))
+		}
+
+		converted, err := toGo(value, field.Type())
+		if err != nil {
+			panic(NewRuntimeError(name, err.Error()))
+		}
+		field.Set(converted)
+		return
+	}
+
+	panic(NewRuntimeError(name,
+		fmt.Sprintf("Undefined field '%s'.", name.Lexeme)))
+}
+
+func (h *HostObject) String() string {
+	if stringer, ok := h.value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return h.typeName() + " instance"
+}
+
+// field finds the exported field called name. A field promoted through a
+// nil embedded pointer is found but invalid, so it reads as nil.
+func (h *HostObject) field(name string) (reflect.Value, bool) {
+	sfield, ok := h.value.Elem().Type().FieldByName(name)
+	if !ok || !sfield.IsExported() {
+		return reflect.Value{}, false
+	}
+	field, err := h.value.Elem().FieldByIndexErr(sfield.Index)
+	if err != nil {
+		return reflect.Value{}, true
+	}
+	return field, true
+}
+
+func (h *HostObject) typeName() string {
+	return h.value.Elem().Type().Name()
+}
+
+// GoValue is an opaque handle for a Go value without a Lox equivalent, such
+// as a channel or a function NewGoFunc can't call. Scripts can pass it
+// around and compare it, and it is unwrapped when handed back to Go.
+type GoValue struct {
+	value reflect.Value
+}
+
+// Value returns the wrapped Go value.
+func (g *GoValue) Value() any {
+	return g.value.Interface()
+}
+
+func (g *GoValue) String() string {
+	return fmt.Sprintf("<go %s>", g.value.Type())
+}
+
+// hostNames lists the Go identifiers a Lox property name may refer to.
+func hostNames(name string) []string {
+	first, size := utf8.DecodeRuneInString(name)
+	exported := string(unicode.ToUpper(first)) + name[size:]
+	if exported == name {
+		return []string{name}
+	}
+	return []string{name, exported}
+}
+
+// Define binds a Go value to a global Lox variable, converting it to a Lox
+// value. Struct pointers become host objects.
+func (i *Interpreter) Define(name string, value any) {
+	i.builtins.Define(name, ToLox(value))
+}
@@ -0,0 +1,310 @@
+package lox_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+type Address struct {
+	City string
+}
+
+type User struct {
+	Name      string
+	Age       int
+	Addr      Address
+	Tags      []string
+	Meta      map[string]int
+	Addresses []Address
+	secret    string
+}
+
+func (u *User) Greet(greeting string) string {
+	return greeting + ", " + u.Name
+}
+
+func (u *User) Birthday() {
+	u.Age++
+}
+
+func newUser() *User {
+	return &User{
+		Name:      "Ann",
+		Age:       30,
+		Addr:      Address{City: "Oslo"},
+		Tags:      []string{"x"},
+		Meta:      map[string]int{"k": 1},
+		Addresses: []Address{{City: "Rome"}},
+		secret:    "hidden",
+	}
+}
+
+func TestHostFields(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		user := newUser()
+		l.Interpreter().Define("user", user)
+
+		err := l.Run(`
+print user.name;
+print user.Age;
+user.name = "Bob";
+user.age = user.age + 1;
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "Ann\n30\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		if user.Name != "Bob" || user.Age != 31 {
+			t.Errorf("got %q, %d, want Bob, 31", user.Name, user.Age)
+		}
+
+		tests := []struct {
+			source string
+			want   string
+		}{
+			{`user.age = "old";`, "Expected int but got string."},
+			{`print user.secret;`, "Undefined property 'secret'."},
+			{`user.secret = "";`, "Undefined field 'secret'."},
+		}
+		for _, test := range tests {
+			err := l.Run(test.source)
+			if got := runtimeError(t, err); got != test.want {
+				t.Errorf("%s: got %q, want %q", test.source, got, test.want)
+			}
+		}
+	})
+}
+
+func TestHostMethods(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		user := newUser()
+		l.Interpreter().Define("user", user)
+
+		if err := l.Run(`print user.greet("Hi"); user.Birthday();`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "Hi, Ann\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		if user.Age != 31 {
+			t.Errorf("got age %d, want 31", user.Age)
+		}
+	})
+}
+
+func TestHostNestedStructs(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		user := newUser()
+		l.Interpreter().Define("user", user)
+
+		err := l.Run(`
+print user.addr.city;
+user.addr.city = "Bergen";
+var first = user.addresses[0];
+first.city = "Milan";
+print user.addr.city;
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "Oslo\nBergen\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		if user.Addr.City != "Bergen" {
+			t.Errorf("got city %q, want Bergen", user.Addr.City)
+		}
+		if user.Addresses[0].City != "Milan" {
+			t.Errorf("got city %q, want Milan", user.Addresses[0].City)
+		}
+	})
+}
+
+func TestHostSlicesAndMaps(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		user := newUser()
+		l.Interpreter().Define("user", user)
+
+		err := l.Run(`
+print user.tags;
+print user.meta;
+var tags = user.tags.slice(0);
+tags.push("y");
+user.tags = tags;
+user.meta = {"k": 5, "j": 2};
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "[x]\n{k: 1}\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		if want := []string{"x", "y"}; !reflect.DeepEqual(user.Tags, want) {
+			t.Errorf("got tags %v, want %v", user.Tags, want)
+		}
+		if want := map[string]int{"k": 5, "j": 2}; !reflect.DeepEqual(user.Meta, want) {
+			t.Errorf("got meta %v, want %v", user.Meta, want)
+		}
+
+		tests := []struct {
+			source string
+			want   string
+		}{
+			{`user.tags.push("z");`, "Can't change a list copied from a Go field."},
+			{`user.tags[0] = "z";`, "Can't change a list copied from a Go field."},
+			{`user.meta["k"] = 6;`, "Can't change a map copied from a Go field."},
+			{`user.meta.delete("k");`, "Can't change a map copied from a Go field."},
+			{`user.tags = [1];`, "Expected string but got number."},
+		}
+		for _, test := range tests {
+			err := l.Run(test.source)
+			if got := runtimeError(t, err); got != test.want {
+				t.Errorf("%s: got %q, want %q", test.source, got, test.want)
+			}
+		}
+		if want := []string{"x", "y"}; !reflect.DeepEqual(user.Tags, want) {
+			t.Errorf("got tags %v, want %v", user.Tags, want)
+		}
+	})
+}
+
+func TestHostRoundTrip(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		l := newLox(engine, &strings.Builder{})
+		i := l.Interpreter()
+		err := i.DefineFunc("total", func(values []int, weights map[string]float64) float64 {
+			sum := 0.0
+			for _, value := range values {
+				sum += float64(value) * weights["w"]
+			}
+			return sum
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		i.Define("values", []int{1, 2, 3})
+
+		if err := l.Run(`var result = total(values, {"w": 2}); values.push(4);`); err != nil {
+			t.Fatal(err)
+		}
+		if result, _ := i.Global("result"); result != 12.0 {
+			t.Errorf("got %v, want 12", result)
+		}
+		values, _ := i.Global("values")
+		if want := []any{1.0, 2.0, 3.0, 4.0}; !reflect.DeepEqual(values, want) {
+			t.Errorf("got %v, want %v", values, want)
+		}
+	})
+}
+
+type Base struct {
+	ID int
+}
+
+type Item struct {
+	*Base
+	Name string
+}
+
+type Inner struct {
+	Value int
+}
+
+type Outer struct {
+	In Inner
+}
+
+func TestHostEmbeddedNil(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		l.Interpreter().Define("item", &Item{Name: "a"})
+
+		if err := l.Run(`print item.ID; print item.base;`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "nil\nnil\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		err := l.Run(`item.ID = 1;`)
+		if got, want := runtimeError(t, err), "Can't set field 'ID' through a nil embedded struct."; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestHostEquality(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		l.Interpreter().Define("o", &Outer{})
+
+		if err := l.Run(`print o == o; print o == o.in; print o.in == o.in;`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "true\nfalse\ntrue\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+	})
+}
+
+func TestGoValues(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		i := l.Interpreter()
+		i.Define("f", func() (int, int, int) { return 1, 2, 3 })
+		i.Define("g", func() (int, int, int) { return 1, 2, 3 })
+		i.Define("ch", make(chan int))
+		i.Define("nan", map[float64]int{math.NaN(): 1})
+
+		err := l.Run(`
+print f == f;
+print f == g;
+print ch == ch;
+print f;
+print ch;
+var kept = ch;
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "true\nfalse\ntrue\n<go func() (int, int, int)>\n<go chan int>\n"
+		if got := out.String(); got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		if kept, _ := i.Global("kept"); kept == nil {
+			t.Error("got nil, want the channel")
+		} else if _, ok := kept.(chan int); !ok {
+			t.Errorf("got %T, want chan int", kept)
+		}
+
+		tests := []struct {
+			source string
+			want   string
+		}{
+			{`f();`, "Can only call functions and classes."},
+			{`var m = {}; m[f] = 1;`, "Map keys must be strings, numbers, booleans or nil."},
+			{`print nan.len();`, "Only instances have properties."},
+		}
+		for _, test := range tests {
+			err := l.Run(test.source)
+			if got := runtimeError(t, err); got != test.want {
+				t.Errorf("%s: got %q, want %q", test.source, got, test.want)
+			}
+		}
+	})
+}
@@ -2,6 +2,13 @@ package lox
 
 import "fmt"
 
+// Object is implemented by values whose properties can be read and
+// assigned from Lox.
+type Object interface {
+	Get(name *Token) any
+	Set(name *Token, value any)
+}
+
 type Instance struct {
 	class  *LoxClass
 	fields map[string]any
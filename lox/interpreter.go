@@ -1,40 +1,111 @@
 package lox
 
 import (
+	"context"
 	"fmt"
 	"strings"
-	"time"
+	"sync"
 )
 
 type Interpreter struct {
+	lox         *Lox
 	environment *Environment
 	globals     *Environment
+	builtins    *Environment
 	locals      map[Expr]int
-}
-
-func NewInterpreter() *Interpreter {
-	globals := NewEnvironment(nil)
-	arity := func() int {
-		return 0
-	}
-	call := func(i *Interpreter, args []any) any {
-		return time.Now().UnixMilli()
+	frames      []frame
+	depth       int
+	active      bool
+	ctx         context.Context
+	steps       int
+	location    *Token
+	vm          *VM
+	printing    map[any]bool
+	modules     map[string]*LoxModule
+	files       map[*Environment]string
+	importing   []string
+	// callers are the frames below the running generator, and
+	// callerDepth is their call depth.
+	callers        []frame
+	callerDepth    int
+	coroutine      *coroutine
+	abandoned      []*generator
+	abandonedMutex sync.Mutex
+	// coroutines are the started coroutines that haven't finished.
+	coroutines map[*coroutine]bool
+	// closed counts the calls of Lox.Close.
+	closed int
+}
+
+func NewInterpreter(lox *Lox) *Interpreter {
+	builtins := NewEnvironment(nil)
+	globals := NewEnvironment(builtins)
+	interpreter := &Interpreter{
+		lox:         lox,
+		environment: globals,
+		globals:     globals,
+		builtins:    builtins,
+		locals:      make(map[Expr]int),
+		printing:    make(map[any]bool),
+		modules:     make(map[string]*LoxModule),
+		files:       make(map[*Environment]string),
+		coroutines:  make(map[*coroutine]bool),
 	}
-	globals.Define("clock", NewNativeFunc(arity, call))
-	return &Interpreter{globals, globals, make(map[Expr]int)}
+	interpreter.vm = NewVM(interpreter)
+	interpreter.defineStdlib()
+	return interpreter
 }
 
-func (i *Interpreter) Interpret(statements []Stmt) {
-	for _, stmt := range statements {
-		i.execute(stmt)
+func (i *Interpreter) Interpret(statements []Stmt) error {
+	err := i.protect(func() {
+		for _, stmt := range statements {
+			i.execute(stmt)
+		}
+	})
+	if err != nil {
+		i.lox.Report(err)
 	}
+	return err
+}
+
+// protect runs fn and returns the runtime error raised by it, if any,
+// restoring the environment and call stack the error unwound through.
+func (i *Interpreter) protect(fn func()) (err error) {
+	environment := i.environment
+	globals := i.globals
+	frames := len(i.frames)
+	depth := i.depth
+	vm := i.vm.save()
+
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeError, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			if runtimeError.Frames == nil {
+				runtimeError.Frames = i.traceback(runtimeError.Token)
+			}
+			i.environment = environment
+			i.globals = globals
+			i.frames = i.frames[:frames]
+			i.depth = depth
+			i.vm.restore(vm)
+			err = runtimeError
+		}
+	}()
+
+	fn()
+	return nil
 }
 
 func (i *Interpreter) evaluate(expr Expr) any {
+	i.step(exprToken(expr))
 	return expr.Accept(i)
 }
 
 func (i *Interpreter) execute(stmt Stmt) {
+	i.step(stmtToken(stmt))
 	stmt.Accept(i)
 }
 
@@ -44,13 +115,14 @@ func (i *Interpreter) Resolve(expr Expr, depth int) {
 
 func (i *Interpreter) executeBlock(statements []Stmt, environment *Environment) {
 	previous := i.environment
+	defer func() {
+		i.environment = previous
+	}()
 	i.environment = environment
 
 	for _, statement := range statements {
 		i.execute(statement)
 	}
-
-	i.environment = previous
 }
 
 func (i *Interpreter) VisitBlockStmt(stmt *Block) any {
@@ -79,20 +151,38 @@ func (i *Interpreter) VisitClassStmt(stmt *Class) any {
 		i.environment.Define("super", superclass)
 	}
 
-	methods := make(map[string]*LoxFunction)
+	methods := make(map[string]Method)
+	functions := make([]*LoxFunction, 0, len(stmt.Methods))
 	for _, method := range stmt.Methods {
-		function := NewLoxFunction(method, i.environment,
+		function := NewLoxFunction(method, i.environment, i.globals,
 			method.Name.Lexeme == "init")
 		methods[method.Name.Lexeme] = function
+		functions = append(functions, function)
 	}
 
 	class := NewLoxClass(stmt.Name.Lexeme, superclass, methods)
+	for _, method := range stmt.ClassMethods {
+		function := NewLoxFunction(method, i.environment, i.globals, false)
+		class.ClassMethods[method.Name.Lexeme] = function
+		functions = append(functions, function)
+	}
+	for _, function := range functions {
+		function.Class = class
+	}
 
 	if superclass != nil {
 		i.environment = i.environment.Enclosing
 	}
 
 	i.environment.Assign(stmt.Name, class)
+
+	for _, field := range stmt.Fields {
+		var value any
+		if field.Initializer != nil {
+			value = i.evaluate(field.Initializer)
+		}
+		class.Fields[field.Name.Lexeme] = value
+	}
 	return nil
 }
 
@@ -102,7 +192,7 @@ func (i *Interpreter) VisitExpressionStmt(stmt *Expression) any {
 }
 
 func (i *Interpreter) VisitFunctionStmt(stmt *Function) any {
-	function := NewLoxFunction(stmt, i.environment, false)
+	function := NewLoxFunction(stmt, i.environment, i.globals, false)
 	i.environment.Define(stmt.Name.Lexeme, function)
 	return nil
 }
@@ -116,9 +206,34 @@ func (i *Interpreter) VisitIfStmt(stmt *If) any {
 	return nil
 }
 
+// VisitMatchStmt runs the first case with a pattern matching the value and
+// a truthy guard, or the default case.
+func (i *Interpreter) VisitMatchStmt(stmt *Match) any {
+	value := i.evaluate(stmt.Value)
+	for k, patterns := range stmt.Patterns {
+		matched := false
+		for _, pattern := range patterns {
+			if i.matches(value, i.evaluate(pattern), stmt.Keyword) {
+				matched = true
+				break
+			}
+		}
+		if !matched || stmt.Guards[k] != nil && !i.isTruthy(i.evaluate(stmt.Guards[k])) {
+			continue
+		}
+		i.execute(stmt.Bodies[k])
+		return nil
+	}
+
+	if stmt.Otherwise != nil {
+		i.execute(stmt.Otherwise)
+	}
+	return nil
+}
+
 func (i *Interpreter) VisitPrintStmt(stmt *Print) any {
 	value := i.evaluate(stmt.Expression)
-	fmt.Println(i.stringify(value))
+	fmt.Fprintln(i.lox.stdout, i.stringify(value, stmt.Keyword))
 	return nil
 }
 
@@ -130,6 +245,48 @@ func (i *Interpreter) VisitReturnStmt(stmt *Return) any {
 	panic(NewReturnValue(value))
 }
 
+func (i *Interpreter) VisitThrowStmt(stmt *Throw) any {
+	panic(i.thrown(stmt.Keyword, i.evaluate(stmt.Value)))
+}
+
+// VisitTryStmt runs the finally block however the try statement is left:
+// normally, by an error, or by return, break and continue unwinding
+// through it. If the finally block itself returns or throws, that
+// replaces the pending unwinding. Fatal errors skip both handlers.
+func (i *Interpreter) VisitTryStmt(stmt *Try) any {
+	var err *RuntimeError
+	if stmt.Finally != nil {
+		defer func() {
+			if err == nil || !err.Fatal {
+				i.executeBlock(stmt.Finally, NewEnvironment(i.environment))
+			}
+		}()
+	}
+
+	err = i.try(func() {
+		i.executeBlock(stmt.Body, NewEnvironment(i.environment))
+	})
+	if err != nil && !err.Fatal && stmt.Name != nil {
+		environment := NewEnvironment(i.environment)
+		environment.Define(stmt.Name.Lexeme, caught(err))
+		err = i.try(func() {
+			i.executeBlock(stmt.Handler, environment)
+		})
+	}
+	if err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+// try is protect for code that may recover from the error.
+func (i *Interpreter) try(fn func()) *RuntimeError {
+	if err := i.protect(fn); err != nil {
+		return err.(*RuntimeError)
+	}
+	return nil
+}
+
 func (i *Interpreter) VisitVarStmt(stmt *Var) any {
 	var value any
 	if stmt.Initializer != nil {
@@ -141,75 +298,99 @@ func (i *Interpreter) VisitVarStmt(stmt *Var) any {
 
 func (i *Interpreter) VisitWhileStmt(stmt *While) any {
 	for i.isTruthy(i.evaluate(stmt.Condition)) {
-		i.execute(stmt.Body)
+		if i.executeLoopBody(stmt.Body, i.environment) == BREAK {
+			break
+		}
+		if stmt.Increment != nil {
+			i.evaluate(stmt.Increment)
+		}
 	}
 	return nil
 }
 
-func (i *Interpreter) VisitAssignExpr(expr *Assign) any {
-	value := i.evaluate(expr.Value)
-
-	distance, ok := i.locals[expr]
-	if ok {
-		i.environment.AssignAt(distance, expr.Name, value)
-	} else {
-		i.globals.Assign(expr.Name, value)
+// VisitForInStmt runs the body in a new environment for every value, so
+// closures created by the body capture the value of their iteration.
+func (i *Interpreter) VisitForInStmt(stmt *ForIn) any {
+	iterator := i.iterate(i.evaluate(stmt.Iterable), stmt.Keyword)
+	for {
+		value, ok := iterator.Next(i)
+		if !ok {
+			break
+		}
+		environment := NewEnvironment(i.environment)
+		environment.Define(stmt.Name.Lexeme, value)
+		if i.executeLoopBody(stmt.Body, environment) == BREAK {
+			break
+		}
 	}
-
-	return value
+	return nil
 }
 
-func (i *Interpreter) VisitBinaryExpr(expr *Binary) any {
-	left := i.evaluate(expr.Left)
-	right := i.evaluate(expr.Right)
+func (i *Interpreter) VisitYieldStmt(stmt *Yield) any {
+	var value any
+	if stmt.Value != nil {
+		value = i.evaluate(stmt.Value)
+	}
+	i.coroutine.yield(i, stmt.Keyword, value)
+	return nil
+}
 
-	switch expr.Operator.Type {
-	case GREATER:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) > right.(float64)
-	case GREATER_EQUAL:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) >= right.(float64)
-	case LESS:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) < right.(float64)
-	case LESS_EQUAL:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) <= right.(float64)
-	case MINUS:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) - right.(float64)
-	case BANG_EQUAL:
-		return !i.isEqual(left, right)
-	case EQUAL_EQUAL:
-		return i.isEqual(left, right)
-	case PLUS:
-		lval, lok := left.(float64)
-		rval, rok := right.(float64)
-		if lok && rok {
-			return lval + rval
+// executeLoopBody runs one iteration of a loop in environment and returns
+// BREAK or CONTINUE if the body was left early.
+func (i *Interpreter) executeLoopBody(body Stmt, environment *Environment) (signal TokenType) {
+	previous := i.environment
+	i.environment = environment
+	defer func() {
+		i.environment = previous
+		if r := recover(); r != nil {
+			loopSignal, ok := r.(*LoopSignal)
+			if !ok {
+				panic(r)
+			}
+			signal = loopSignal.Keyword.Type
 		}
+	}()
 
-		lstr, lok := left.(string)
-		rstr, rok := right.(string)
-		if lok && rok {
-			return lstr + rstr
-		}
+	i.execute(body)
+	return EOF
+}
 
-		panic(NewRuntimeError(
-			expr.Operator,
-			"Operands must be two numbers or strings.",
-		))
-	case SLASH:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) / right.(float64)
-	case STAR:
-		i.checkNumberOperands(expr.Operator, left, right)
-		return left.(float64) * right.(float64)
+func (i *Interpreter) VisitExportStmt(stmt *Export) any {
+	i.execute(stmt.Declaration)
+	return nil
+}
+
+func (i *Interpreter) VisitImportStmt(stmt *Import) any {
+	module := i.importModule(stmt.Keyword, stmt.Path.Literal.(string), i.globals)
+	if stmt.Alias != nil {
+		i.environment.Define(stmt.Alias.Lexeme, module)
+	}
+	for _, name := range stmt.Names {
+		i.environment.Define(name.Lexeme, module.Get(name))
 	}
 	return nil
 }
 
+func (i *Interpreter) VisitBreakStmt(stmt *Break) any {
+	panic(NewLoopSignal(stmt.Keyword))
+}
+
+func (i *Interpreter) VisitContinueStmt(stmt *Continue) any {
+	panic(NewLoopSignal(stmt.Keyword))
+}
+
+func (i *Interpreter) VisitAssignExpr(expr *Assign) any {
+	value := i.evaluate(expr.Value)
+	i.assignVariable(expr.Name, expr, value)
+	return value
+}
+
+func (i *Interpreter) VisitBinaryExpr(expr *Binary) any {
+	left := i.evaluate(expr.Left)
+	right := i.evaluate(expr.Right)
+	return i.binaryOp(expr.Operator, left, right)
+}
+
 func (i *Interpreter) VisitCallExpr(expr *Call) any {
 	callee := i.evaluate(expr.Callee)
 
@@ -217,38 +398,79 @@ func (i *Interpreter) VisitCallExpr(expr *Call) any {
 	for _, argument := range expr.Arguments {
 		arguments = append(arguments, i.evaluate(argument))
 	}
+	if len(expr.Names) > 0 {
+		arguments = i.bindArguments(callee, arguments, keywordNames(expr.Names), expr.Paren)
+	}
+
+	return i.callValue(callee, arguments, expr.Paren)
+}
 
-	function, ok := callee.(Callable)
-	if !ok {
-		panic(NewRuntimeError(
-			expr.Paren, "Can only call functions and classes.",
-		))
+// VisitCompoundExpr evaluates the parts of the target once, and uses them
+// both to read the old value and to store the new one.
+func (i *Interpreter) VisitCompoundExpr(expr *Compound) any {
+	var old, value any
+	apply := func(old any) any {
+		return i.binaryOp(expr.Operator, old, i.evaluate(expr.Value))
 	}
 
-	if len(arguments) != function.Arity() {
-		panic(NewRuntimeError(
-			expr.Paren,
-			fmt.Sprintf("Expected %d arguments but got %d.",
-				function.Arity(), len(arguments)),
-		))
+	switch target := expr.Target.(type) {
+	case *Variable:
+		old = i.lookupVariable(target.Name, target)
+		value = apply(old)
+		i.assignVariable(target.Name, target, value)
+	case *Get:
+		object := i.evaluate(target.Object)
+		old = i.getProperty(object, target.Name)
+		value = apply(old)
+		i.setProperty(object, target.Name, value)
+	case *Index:
+		object := i.evaluate(target.Object)
+		index := i.evaluate(target.Index)
+		old = i.getIndex(object, index, target.Bracket)
+		value = apply(old)
+		i.setIndex(object, index, value, target.Bracket)
 	}
 
-	return function.Call(i, arguments)
+	if expr.Postfix {
+		return old
+	}
+	return value
+}
+
+func (i *Interpreter) VisitConditionalExpr(expr *Conditional) any {
+	if i.isTruthy(i.evaluate(expr.Condition)) {
+		return i.evaluate(expr.ThenBranch)
+	}
+	return i.evaluate(expr.ElseBranch)
 }
 
 func (i *Interpreter) VisitGetExpr(expr *Get) any {
 	object := i.evaluate(expr.Object)
-	if val, ok := object.(*Instance); ok {
-		return val.Get(expr.Name)
-	}
-	panic(NewRuntimeError(expr.Name,
-		"Only instances have properties."))
+	return i.getProperty(object, expr.Name)
 }
 
 func (i *Interpreter) VisitGroupingExpr(expr *Grouping) any {
 	return i.evaluate(expr.Expression)
 }
 
+func (i *Interpreter) VisitIndexExpr(expr *Index) any {
+	object := i.evaluate(expr.Object)
+	index := i.evaluate(expr.Index)
+	return i.getIndex(object, index, expr.Bracket)
+}
+
+func (i *Interpreter) VisitLambdaExpr(expr *Lambda) any {
+	return NewLoxFunction(expr.Function, i.environment, i.globals, false)
+}
+
+func (i *Interpreter) VisitListExpr(expr *List) any {
+	elements := make([]any, len(expr.Elements))
+	for k, element := range expr.Elements {
+		elements[k] = i.evaluate(element)
+	}
+	return NewLoxList(elements)
+}
+
 func (i *Interpreter) VisitLiteralExpr(expr *Literal) any {
 	return expr.Value
 }
@@ -269,33 +491,43 @@ func (i *Interpreter) VisitLogicalExpr(expr *Logical) any {
 	return i.evaluate(expr.Right)
 }
 
+func (i *Interpreter) VisitMapExpr(expr *Map) any {
+	entries := make([]any, 0, 2*len(expr.Keys))
+	for k := range expr.Keys {
+		entries = append(entries, i.evaluate(expr.Keys[k]), i.evaluate(expr.Values[k]))
+	}
+	return i.newMap(entries, expr.Brace)
+}
+
 func (i *Interpreter) VisitSetExpr(expr *Set) any {
 	object := i.evaluate(expr.Object)
-
-	if _, ok := object.(*Instance); !ok {
+	if _, ok := object.(Object); !ok {
 		panic(NewRuntimeError(expr.Name,
 			"Only instances have fields."))
 	}
 
 	value := i.evaluate(expr.Value)
-	object.(*Instance).Set(expr.Name, value)
+	i.setProperty(object, expr.Name, value)
+	return value
+}
+
+func (i *Interpreter) VisitSetIndexExpr(expr *SetIndex) any {
+	object := i.evaluate(expr.Object)
+	index := i.evaluate(expr.Index)
+	value := i.evaluate(expr.Value)
+	i.setIndex(object, index, value, expr.Bracket)
 	return value
 }
 
+func (i *Interpreter) VisitStringifyExpr(expr *Stringify) any {
+	return i.stringify(i.evaluate(expr.Expression), expr.Token)
+}
+
 func (i *Interpreter) VisitSuperExpr(expr *Super) any {
 	distance := i.locals[expr]
 	superclass := i.environment.GetAt(distance, "super").(*LoxClass)
-	object := i.environment.GetAt(distance-1, "this").(*Instance)
-	method := superclass.FindMethod(expr.Method.Lexeme)
-
-	if method == nil {
-		panic(NewResolveError(
-			expr.Method,
-			fmt.Sprint("Undefined property '%s'.", expr.Method.Lexeme),
-		))
-	}
-
-	return method.Bind(object)
+	object := i.environment.GetAt(distance-1, "this")
+	return i.superMethod(superclass, object, expr.Method)
 }
 
 func (i *Interpreter) VisitThisExpr(expr *This) any {
@@ -304,22 +536,22 @@ func (i *Interpreter) VisitThisExpr(expr *This) any {
 
 func (i *Interpreter) VisitUnaryExpr(expr *Unary) any {
 	right := i.evaluate(expr.Right)
-
-	switch expr.Operator.Type {
-	case MINUS:
-		i.checkNumberOperand(expr.Operator, right)
-		return right.(float64)
-	case BANG:
-		return !i.isTruthy(right)
-	}
-
-	return nil
+	return i.unaryOp(expr.Operator, right)
 }
 
 func (i *Interpreter) VisitVariableExpr(expr *Variable) any {
 	return i.lookupVariable(expr.Name, expr)
 }
 
+func (i *Interpreter) assignVariable(name *Token, expr Expr, value any) {
+	distance, ok := i.locals[expr]
+	if ok {
+		i.environment.AssignAt(distance, name, value)
+	} else {
+		i.globals.Assign(name, value)
+	}
+}
+
 func (i *Interpreter) lookupVariable(name *Token, expr Expr) any {
 	distance, ok := i.locals[expr]
 	if ok {
@@ -343,7 +575,7 @@ func (i *Interpreter) checkNumberOperands(operator *Token, left, right any) {
 		return
 	}
 
-	panic(NewRuntimeError(operator, "Operands must be numbers"))
+	panic(NewRuntimeError(operator, "Operands must be numbers."))
 }
 
 func (i *Interpreter) isTruthy(object any) bool {
@@ -356,11 +588,31 @@ func (i *Interpreter) isTruthy(object any) bool {
 	return true
 }
 
-func (i *Interpreter) isEqual(a, b any) bool {
+// isEqual compares a and b for == at token. An instance on the left whose
+// class has __eq__ decides by the truthiness of its result.
+func (i *Interpreter) isEqual(a, b any, token *Token) bool {
+	if method := specialMethod(a, "__eq__"); method != nil {
+		return i.isTruthy(i.callValue(method, []any{b}, token))
+	}
+	switch a := a.(type) {
+	case *HostObject:
+		// A struct has the same address as its first field, so the types
+		// must match too.
+		if b, ok := b.(*HostObject); ok {
+			return a.value.Type() == b.value.Type() && a.value.Pointer() == b.value.Pointer()
+		}
+	case *GoValue:
+		if b, ok := b.(*GoValue); ok {
+			return a == b || a.value.Type() == b.value.Type() &&
+				a.value.Comparable() && a.value.Equal(b.value)
+		}
+	}
 	return a == b
 }
 
-func (i *Interpreter) stringify(object any) string {
+// stringify is the printed form of object. token locates the operation
+// that prints it, for calls of __str__.
+func (i *Interpreter) stringify(object any, token *Token) string {
 	if object == nil {
 		return "nil"
 	}
@@ -373,5 +625,41 @@ func (i *Interpreter) stringify(object any) string {
 		return text
 	}
 
+	if list, ok := object.(*LoxList); ok {
+		if i.printing[list] {
+			return "[...]"
+		}
+		i.printing[list] = true
+		defer delete(i.printing, list)
+
+		elements := make([]string, len(list.Elements))
+		for k, element := range list.Elements {
+			elements[k] = i.stringify(element, token)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+
+	if m, ok := object.(*LoxMap); ok {
+		if i.printing[m] {
+			return "{...}"
+		}
+		i.printing[m] = true
+		defer delete(i.printing, m)
+
+		entries := make([]string, len(m.keys))
+		for k, key := range m.keys {
+			entries[k] = i.stringify(key, token) + ": " + i.stringify(m.entries[key], token)
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	}
+
+	if method := specialMethod(object, "__str__"); method != nil {
+		text, ok := i.callValue(method, nil, token).(string)
+		if !ok {
+			panic(NewRuntimeError(token, "__str__ must return a string."))
+		}
+		return text
+	}
+
 	return fmt.Sprintf("%v", object)
 }
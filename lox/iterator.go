@@ -0,0 +1,106 @@
+package lox
+
+import "fmt"
+
+// Iterator produces the values of a for-in loop. Next reports false once
+// there are no more values.
+type Iterator interface {
+	Next(interpreter *Interpreter) (any, bool)
+}
+
+// iterate returns an iterator over the elements of a list, the keys of a
+// map, the characters of a string or the numbers of a range. An instance
+// whose class has an iterator method is replaced by its result, and an
+// instance with a next method is iterated until next returns nil. A
+// generator is iterated until its body finishes.
+func (i *Interpreter) iterate(iterable any, keyword *Token) Iterator {
+	if instance, ok := iterable.(*Instance); ok {
+		if method := instance.class.FindMethod("iterator"); method != nil {
+			iterable = i.callValue(method.Bind(instance), nil, keyword)
+		}
+	}
+
+	switch iterable := iterable.(type) {
+	case Iterator:
+		return iterable
+	case *LoxGenerator:
+		return &generatorIterator{iterable, keyword}
+	case *LoxList:
+		return &listIterator{list: iterable}
+	case *LoxRange:
+		return &rangeIterator{iterable, 0}
+	case *LoxMap:
+		return &listIterator{list: NewLoxList(iterable.Keys())}
+	case string:
+		characters := make([]any, 0, len(iterable))
+		for _, character := range iterable {
+			characters = append(characters, string(character))
+		}
+		return &listIterator{list: NewLoxList(characters)}
+	case *Instance:
+		if method := iterable.class.FindMethod("next"); method != nil {
+			return &objectIterator{method.Bind(iterable), keyword}
+		}
+	}
+	panic(NewRuntimeError(keyword,
+		"Can only iterate over lists, maps, strings, ranges and iterators."))
+}
+
+// listIterator walks a list by index, so it sees elements added during
+// the loop.
+type listIterator struct {
+	list  *LoxList
+	index int
+}
+
+func (l *listIterator) Next(interpreter *Interpreter) (any, bool) {
+	if l.index >= len(l.list.Elements) {
+		return nil, false
+	}
+	l.index++
+	return l.list.Elements[l.index-1], true
+}
+
+// objectIterator calls the next method of a Lox object.
+type objectIterator struct {
+	next    Method
+	keyword *Token
+}
+
+func (o *objectIterator) Next(interpreter *Interpreter) (any, bool) {
+	value := interpreter.callValue(o.next, nil, o.keyword)
+	return value, value != nil
+}
+
+// LoxRange is the sequence of numbers from Start up to, but not including,
+// End in increments of Step.
+type LoxRange struct {
+	Start, End, Step float64
+}
+
+func NewLoxRange(start, end, step float64) *LoxRange {
+	return &LoxRange{start, end, step}
+}
+
+func (r *LoxRange) String() string {
+	return fmt.Sprintf("range(%v, %v, %v)", r.Start, r.End, r.Step)
+}
+
+type rangeIterator struct {
+	r     *LoxRange
+	index int
+}
+
+// Next computes every value from the start, so that no rounding error
+// builds up, and compares it with the end, which may be infinite.
+func (r *rangeIterator) Next(interpreter *Interpreter) (any, bool) {
+	value := r.r.Start
+	if r.index > 0 {
+		value += float64(r.index) * r.r.Step
+	}
+	if (r.r.Step > 0 && value >= r.r.End) || (r.r.Step < 0 && value <= r.r.End) {
+		return nil, false
+	}
+	r.index++
+	return value, true
+}
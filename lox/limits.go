@@ -0,0 +1,204 @@
+package lox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxCallDepth bounds recursion when no limit is configured, so
+// runaway Lox recursion fails with a runtime error before it exhausts the
+// Go stack.
+const DefaultMaxCallDepth = 20000
+
+// contextCheckInterval is how many steps run between context polls.
+const contextCheckInterval = 1024
+
+// WithContext sets the context that cancels script execution.
+func WithContext(ctx context.Context) Option {
+	return func(l *Lox) {
+		l.context = ctx
+	}
+}
+
+// WithTimeout bounds the wall-clock time of every Run and Call.
+func WithTimeout(timeout time.Duration) Option {
+	return func(l *Lox) {
+		l.timeout = timeout
+	}
+}
+
+// WithMaxSteps bounds the number of statements and expressions executed
+// by every Run and Call. Zero means no limit.
+func WithMaxSteps(steps int) Option {
+	return func(l *Lox) {
+		l.maxSteps = steps
+	}
+}
+
+// WithMaxCallDepth bounds the depth of nested Lox function calls.
+func WithMaxCallDepth(depth int) Option {
+	return func(l *Lox) {
+		l.maxCallDepth = depth
+	}
+}
+
+// enter starts a top-level execution with a fresh step budget and the
+// engine's deadline. Nested entries, such as natives calling back into
+// Lox, share the budget of the outermost one.
+func (i *Interpreter) enter(ctx context.Context) (exit func()) {
+	if i.active {
+		return func() {}
+	}
+
+	cancel := func() {}
+	if i.lox.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, i.lox.timeout)
+	}
+
+	i.active = true
+	i.ctx = ctx
+	i.steps = 0
+	return func() {
+		cancel()
+		i.active = false
+		i.ctx = nil
+	}
+}
+
+// step accounts for one statement or expression and stops execution once
+// a limit is hit. token is the location of the node, if it has one.
+func (i *Interpreter) step(token *Token) {
+	if token != nil {
+		i.location = token
+	}
+
+	i.steps++
+	i.checkLimits()
+}
+
+// checkLimits stops execution if the step budget is spent or, every
+// contextCheckInterval steps, if the context is done.
+func (i *Interpreter) checkLimits() {
+	if i.lox.maxSteps > 0 && i.steps > i.lox.maxSteps {
+		err := NewRuntimeError(i.where(),
+			fmt.Sprintf("Step limit of %d exceeded.", i.lox.maxSteps))
+		err.Fatal = true
+		panic(err)
+	}
+
+	if i.ctx != nil && i.steps%contextCheckInterval == 0 {
+		if err := i.ctx.Err(); err != nil {
+			message := "Execution cancelled."
+			if errors.Is(err, context.DeadlineExceeded) {
+				message = "Execution timed out."
+			}
+			err := NewRuntimeError(i.where(), message)
+			err.Fatal = true
+			panic(err)
+		}
+	}
+}
+
+// checkCallDepth is called on entry to a Lox function.
+func (i *Interpreter) checkCallDepth() {
+	limit := i.lox.maxCallDepth
+	if limit <= 0 {
+		limit = DefaultMaxCallDepth
+	}
+	if i.depth+i.callerDepth >= limit {
+		err := NewRuntimeError(i.callSite(),
+			fmt.Sprintf("Stack overflow: call depth limit of %d exceeded.", limit))
+		err.Fatal = true
+		panic(err)
+	}
+}
+
+// where is the best known location of the code being executed.
+func (i *Interpreter) where() *Token {
+	if i.location != nil {
+		return i.location
+	}
+	return i.callSite()
+}
+
+func stmtToken(stmt Stmt) *Token {
+	switch stmt := stmt.(type) {
+	case *Break:
+		return stmt.Keyword
+	case *Class:
+		return stmt.Name
+	case *Continue:
+		return stmt.Keyword
+	case *Export:
+		return stmt.Keyword
+	case *ForIn:
+		return stmt.Keyword
+	case *Function:
+		return stmt.Name
+	case *If:
+		return stmt.Keyword
+	case *Import:
+		return stmt.Keyword
+	case *Match:
+		return stmt.Keyword
+	case *Print:
+		return stmt.Keyword
+	case *Return:
+		return stmt.Keyword
+	case *Throw:
+		return stmt.Keyword
+	case *Try:
+		return stmt.Keyword
+	case *Var:
+		return stmt.Name
+	case *While:
+		return stmt.Keyword
+	case *Yield:
+		return stmt.Keyword
+	}
+	return nil
+}
+
+func exprToken(expr Expr) *Token {
+	switch expr := expr.(type) {
+	case *Assign:
+		return expr.Name
+	case *Binary:
+		return expr.Operator
+	case *Call:
+		return expr.Paren
+	case *Compound:
+		return expr.Operator
+	case *Conditional:
+		return expr.Question
+	case *Get:
+		return expr.Name
+	case *Index:
+		return expr.Bracket
+	case *Lambda:
+		return expr.Keyword
+	case *List:
+		return expr.Bracket
+	case *Logical:
+		return expr.Operator
+	case *Map:
+		return expr.Brace
+	case *Set:
+		return expr.Name
+	case *SetIndex:
+		return expr.Bracket
+	case *Stringify:
+		return expr.Token
+	case *Super:
+		return expr.Keyword
+	case *This:
+		return expr.Keyword
+	case *Unary:
+		return expr.Operator
+	case *Variable:
+		return expr.Name
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package lox_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"lox/lox"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []lox.Option
+		source  string
+		want    string
+	}{
+		{"steps", []lox.Option{lox.WithMaxSteps(10000)},
+			`while (true) {}`, "Step limit of 10000 exceeded."},
+		{"timeout", []lox.Option{lox.WithTimeout(20 * time.Millisecond)},
+			`while (true) {}`, "Execution timed out."},
+		{"depth", []lox.Option{lox.WithMaxCallDepth(50)},
+			`fun f() { f(); } f();`, "Stack overflow: call depth limit of 50 exceeded."},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+				var out strings.Builder
+				l := newLox(engine, &out, test.options...)
+
+				err := l.Run(test.source)
+				if got := runtimeError(t, err); got != test.want {
+					t.Errorf("got %q, want %q", got, test.want)
+				}
+
+				// Every run gets a fresh budget.
+				if err := l.Run(`print "ok";`); err != nil {
+					t.Fatal(err)
+				}
+				if got := out.String(); got != "ok\n" {
+					t.Errorf("got output %q, want %q", got, "ok\n")
+				}
+			})
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		ctx, cancel := context.WithCancel(context.Background())
+		l := newLox(engine, &out, lox.WithContext(ctx))
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		err := l.Run(`while (true) {}`)
+		if got, want := runtimeError(t, err), "Execution cancelled."; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		if err := l.RunContext(context.Background(), `print "ok";`); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != "ok\n" {
+			t.Errorf("got output %q, want %q", got, "ok\n")
+		}
+	})
+}
+
+func TestCatchLimit(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out, lox.WithMaxSteps(10000))
+
+		err := l.Run(`try { while (true) {} } catch (e) { print "caught"; } finally { print "finally"; }`)
+		if got, want := runtimeError(t, err), "Step limit of 10000 exceeded."; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		if got := out.String(); got != "" {
+			t.Errorf("got output %q, want none", got)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+)
+
+// LoxList is a growable sequence of values created by list literals.
+type LoxList struct {
+	Elements []any
+	// readOnly is set for copies of Go fields, which changes wouldn't reach.
+	readOnly bool
+}
+
+func NewLoxList(elements []any) *LoxList {
+	return &LoxList{elements, false}
+}
+
+// checkWritable fails for lists that can't be changed.
+func (l *LoxList) checkWritable() error {
+	if l.readOnly {
+		return fmt.Errorf("Can't change a list copied from a Go field.")
+	}
+	return nil
+}
+
+// Get returns the built-in method name bound to the list.
+func (l *LoxList) Get(name *Token) any {
+	switch name.Lexeme {
+	case "push":
+		return NewNativeFunc("push", 1, func(i *Interpreter, args []any) (any, error) {
+			if err := l.checkWritable(); err != nil {
+				return nil, err
+			}
+			l.Elements = append(l.Elements, args[0])
+			return nil, nil
+		})
+	case "pop":
+		return NewNativeFunc("pop", 0, func(i *Interpreter, args []any) (any, error) {
+			if err := l.checkWritable(); err != nil {
+				return nil, err
+			}
+			if len(l.Elements) == 0 {
+				return nil, fmt.Errorf("Can't pop from an empty list.")
+			}
+			last := l.Elements[len(l.Elements)-1]
+			l.Elements = l.Elements[:len(l.Elements)-1]
+			return last, nil
+		})
+	case "len":
+		return NewNativeFunc("len", 0, func(i *Interpreter, args []any) (any, error) {
+			return float64(len(l.Elements)), nil
+		})
+	case "insert":
+		return NewNativeFunc("insert", 2, func(i *Interpreter, args []any) (any, error) {
+			if err := l.checkWritable(); err != nil {
+				return nil, err
+			}
+			index, err := listIndex(args[0], len(l.Elements)+1)
+			if err != nil {
+				return nil, err
+			}
+			l.Elements = append(l.Elements, nil)
+			copy(l.Elements[index+1:], l.Elements[index:])
+			l.Elements[index] = args[1]
+			return nil, nil
+		})
+	case "remove":
+		return NewNativeFunc("remove", 1, func(i *Interpreter, args []any) (any, error) {
+			if err := l.checkWritable(); err != nil {
+				return nil, err
+			}
+			index, err := listIndex(args[0], len(l.Elements))
+			if err != nil {
+				return nil, err
+			}
+			removed := l.Elements[index]
+			l.Elements = append(l.Elements[:index], l.Elements[index+1:]...)
+			return removed, nil
+		})
+	case "slice":
+		// The end defaults to the length of the list.
+		slice := func(i *Interpreter, args []any) (any, error) {
+			start, err := listIndex(args[0], len(l.Elements)+1)
+			if err != nil {
+				return nil, err
+			}
+			end := len(l.Elements)
+			if len(args) > 1 && args[1] != nil {
+				end, err = listIndex(args[1], len(l.Elements)+1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if start > end {
+				return nil, fmt.Errorf("Slice start %d is after end %d.", start, end)
+			}
+			elements := make([]any, end-start)
+			copy(elements, l.Elements[start:end])
+			return NewLoxList(elements), nil
+		}
+		return NewNativeFuncRange("slice", 1, 2, slice).WithParameters("start", "end")
+	}
+
+	panic(NewRuntimeError(name,
+		fmt.Sprintf("Undefined property '%s'.", name.Lexeme)))
+}
+
+func (l *LoxList) Set(name *Token, value any) {
+	panic(NewRuntimeError(name, "Only instances have fields."))
+}
+
+// listIndex checks that index is an integer in [0, length).
+func listIndex(index any, length int) (int, error) {
+	number, ok := index.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, fmt.Errorf("List index must be an integer.")
+	}
+	if number < 0 || number >= float64(length) {
+		return 0, fmt.Errorf("List index %v out of range.", number)
+	}
+	return int(number), nil
+}
@@ -2,79 +2,217 @@ package lox
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"time"
 )
 
-var LoxInstance *Lox
-
+// Lox is an embeddable Lox engine. Every engine owns its interpreter
+// state and error sink, so several engines can run side by side.
 type Lox struct {
-	hadError    bool
-	interpreter *Interpreter
+	stdin        io.Reader
+	stdout       io.Writer
+	stderr       io.Writer
+	context      context.Context
+	timeout      time.Duration
+	maxSteps     int
+	maxCallDepth int
+	capabilities map[Capability]bool
+	engine       Engine
+	zeroDivision ZeroDivision
+	modulePath   []string
+	errors       []error
+	interpreter  *Interpreter
+}
+
+// Option configures a Lox engine created by New.
+type Option func(*Lox)
+
+// WithStdin sets the reader used by the interactive prompt.
+func WithStdin(r io.Reader) Option {
+	return func(l *Lox) {
+		l.stdin = r
+	}
+}
+
+// WithStdout sets the writer used by print statements.
+func WithStdout(w io.Writer) Option {
+	return func(l *Lox) {
+		l.stdout = w
+	}
 }
 
-func NewLox() *Lox {
-	return &Lox{false, NewInterpreter()}
+// WithStderr sets the writer errors are reported to.
+func WithStderr(w io.Writer) Option {
+	return func(l *Lox) {
+		l.stderr = w
+	}
 }
 
-func (l *Lox) RunFile(path string) {
-	LoxInstance = l
+func New(options ...Option) *Lox {
+	l := &Lox{
+		stdin:        os.Stdin,
+		stdout:       os.Stdout,
+		stderr:       os.Stderr,
+		context:      context.Background(),
+		capabilities: make(map[Capability]bool),
+		engine:       EngineInterpreter,
+		modulePath:   filepath.SplitList(os.Getenv("LOX_PATH")),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	l.interpreter = NewInterpreter(l)
+	return l
+}
+
+// Interpreter returns the interpreter that executes code for this engine.
+func (l *Lox) Interpreter() *Interpreter {
+	return l.interpreter
+}
+
+func (l *Lox) RunFile(path string) error {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Printf("Failed to read file: %s\n", err)
-		os.Exit(64)
-	}
-	l.Run(string(bytes))
-	// Indicate an error in the exit code.
-	if l.hadError {
-		os.Exit(65)
+		err = fmt.Errorf("Failed to read file: %w", err)
+		fmt.Fprintln(l.stderr, err)
+		return err
 	}
+	l.interpreter.files[l.interpreter.globals] = path
+	return l.Run(string(bytes))
 }
 
-func (l *Lox) RunPrompt() {
-	LoxInstance = l
-	reader := bufio.NewReader(os.Stdin)
+func (l *Lox) RunPrompt() error {
+	reader := bufio.NewReader(l.stdin)
 	for {
-		fmt.Print("> ")
+		fmt.Fprint(l.stdout, "> ")
 		line, err := reader.ReadString('\n')
 		if err == io.EOF {
-			break
+			return nil
 		} else if err != nil {
-			fmt.Printf("Failed to read line: %s\n", err)
-			os.Exit(64)
+			err = fmt.Errorf("Failed to read line: %w", err)
+			fmt.Fprintln(l.stderr, err)
+			return err
 		}
 		l.Run(line)
-		l.hadError = false
 	}
 }
 
-func (l *Lox) Run(source string) {
-	scanner := NewScanner(source)
+// Run executes source and returns the errors reported while doing so.
+// The result can be inspected with errors.As for *ScanError, *ParseError,
+// *ResolveError, *CompileError and *RuntimeError.
+func (l *Lox) Run(source string) error {
+	return l.RunContext(l.context, source)
+}
+
+// RunContext is like Run but stops execution when ctx is done.
+func (l *Lox) RunContext(ctx context.Context, source string) error {
+	l.errors = nil
+	defer l.interpreter.closeAbandoned()
+
+	statements := l.parse(source)
+	if l.hadError() {
+		return l.err()
+	}
+
+	if l.engine == EngineVM {
+		compiler := NewCompiler(l)
+		script := compiler.Compile(statements)
+
+		if l.hadError() {
+			return l.err()
+		}
+
+		exit := l.interpreter.enter(ctx)
+		defer exit()
+
+		l.interpreter.vm.Interpret(script)
+		return l.err()
+	}
+
+	exit := l.interpreter.enter(ctx)
+	defer exit()
+
+	l.interpreter.Interpret(statements)
+	return l.err()
+}
+
+// Close finishes the generators created on the engine, whose next method
+// returns nil afterwards. Generators of the tree-walking interpreter run
+// on goroutines of their own, which keep the engine alive until they
+// finish or it is closed. The engine can still be used after Close.
+func (l *Lox) Close() {
+	l.interpreter.close()
+}
+
+// parse scans, parses and resolves source. It reports errors and returns
+// nil if there were any.
+func (l *Lox) parse(source string) []Stmt {
+	reported := len(l.errors)
+
+	scanner := NewScanner(l, source)
 	tokens := scanner.ScanTokens()
 
-	if l.hadError {
-		return
+	if len(l.errors) > reported {
+		return nil
 	}
 
-	parser := NewParser(tokens)
+	parser := NewParser(l, tokens)
 	statements := parser.Parse()
 
-	if l.hadError {
-		return
+	if len(l.errors) > reported {
+		return nil
 	}
 
-	resolver := NewResolver(l.interpreter)
+	resolver := NewResolver(l, l.interpreter)
 	resolver.ResolveStatements(statements)
 
-	if l.hadError {
-		return
+	if len(l.errors) > reported {
+		return nil
 	}
-
-	l.interpreter.Interpret(statements)
+	return statements
 }
 
 func (l *Lox) Report(error error) {
-	fmt.Fprintln(os.Stderr, error.Error())
-	l.hadError = true
+	fmt.Fprintln(l.stderr, error.Error())
+	if runtimeError, ok := error.(*RuntimeError); ok && len(runtimeError.Frames) > 0 {
+		fmt.Fprintln(l.stderr, runtimeError.Traceback())
+	}
+	l.errors = append(l.errors, error)
+}
+
+func (l *Lox) hadError() bool {
+	return len(l.errors) > 0
+}
+
+func (l *Lox) err() error {
+	return errors.Join(l.errors...)
+}
+
+// ExitCode maps an error returned by Run or RunFile to a conventional
+// process exit code.
+func ExitCode(err error) int {
+	var scanError *ScanError
+	var parseError *ParseError
+	var resolveError *ResolveError
+	var compileError *CompileError
+	var runtimeError *RuntimeError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &scanError),
+		errors.As(err, &parseError),
+		errors.As(err, &resolveError),
+		errors.As(err, &compileError):
+		return 65
+	case errors.As(err, &runtimeError):
+		return 70
+	default:
+		return 64
+	}
 }
@@ -0,0 +1,167 @@
+package lox
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// LoxMap is a dictionary created by map literals. Keys are strings,
+// numbers, booleans or nil, and entries are kept in insertion order.
+type LoxMap struct {
+	keys    []any
+	entries map[any]any
+	// readOnly is set for copies of Go fields, which changes wouldn't reach.
+	readOnly bool
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{entries: make(map[any]any)}
+}
+
+// Keys returns the keys of the map in insertion order.
+func (m *LoxMap) Keys() []any {
+	return slices.Clone(m.keys)
+}
+
+func (m *LoxMap) Len() int {
+	return len(m.keys)
+}
+
+// Lookup returns the entry for key and whether it exists.
+func (m *LoxMap) Lookup(key any) (any, bool, error) {
+	if err := checkKey(key); err != nil {
+		return nil, false, err
+	}
+	value, ok := m.entries[key]
+	return value, ok, nil
+}
+
+// Put adds or replaces an entry. Replacing keeps the original position.
+func (m *LoxMap) Put(key, value any) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if _, ok := m.entries[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key] = value
+	return nil
+}
+
+// Delete removes the entry for key and reports whether it existed.
+func (m *LoxMap) Delete(key any) (bool, error) {
+	if err := m.checkWritable(); err != nil {
+		return false, err
+	}
+	if err := checkKey(key); err != nil {
+		return false, err
+	}
+	if _, ok := m.entries[key]; !ok {
+		return false, nil
+	}
+	delete(m.entries, key)
+	k := slices.Index(m.keys, key)
+	m.keys = slices.Delete(m.keys, k, k+1)
+	return true, nil
+}
+
+// checkWritable fails for maps that can't be changed.
+func (m *LoxMap) checkWritable() error {
+	if m.readOnly {
+		return fmt.Errorf("Can't change a map copied from a Go field.")
+	}
+	return nil
+}
+
+// Get returns the built-in method name bound to the map.
+func (m *LoxMap) Get(name *Token) any {
+	switch name.Lexeme {
+	case "has":
+		return NewNativeFunc("has", 1, func(i *Interpreter, args []any) (any, error) {
+			_, ok, err := m.Lookup(args[0])
+			return ok, err
+		})
+	case "delete":
+		return NewNativeFunc("delete", 1, func(i *Interpreter, args []any) (any, error) {
+			return m.Delete(args[0])
+		})
+	case "keys":
+		return NewNativeFunc("keys", 0, func(i *Interpreter, args []any) (any, error) {
+			return NewLoxList(m.Keys()), nil
+		})
+	case "values":
+		return NewNativeFunc("values", 0, func(i *Interpreter, args []any) (any, error) {
+			values := make([]any, len(m.keys))
+			for k, key := range m.keys {
+				values[k] = m.entries[key]
+			}
+			return NewLoxList(values), nil
+		})
+	case "len":
+		return NewNativeFunc("len", 0, func(i *Interpreter, args []any) (any, error) {
+			return float64(len(m.keys)), nil
+		})
+	}
+
+	panic(NewRuntimeError(name,
+		fmt.Sprintf("Undefined property '%s'.", name.Lexeme)))
+}
+
+func (m *LoxMap) Set(name *Token, value any) {
+	panic(NewRuntimeError(name, "Only instances have fields."))
+}
+
+// checkKey fails for values that can't be map keys.
+func checkKey(key any) error {
+	switch key := key.(type) {
+	case float64:
+		// NaN is unequal to itself, so its entries could never be found.
+		if math.IsNaN(key) {
+			return fmt.Errorf("Map keys can't be NaN.")
+		}
+		return nil
+	case nil, bool, string:
+		return nil
+	}
+	return fmt.Errorf("Map keys must be strings, numbers, booleans or nil.")
+}
+
+// compareKeys orders map keys by type, then by value. It gives maps
+// converted from Go a deterministic order.
+func compareKeys(a, b any) int {
+	rank := func(key any) int {
+		switch key.(type) {
+		case nil:
+			return 0
+		case bool:
+			return 1
+		case float64:
+			return 2
+		case string:
+			return 3
+		}
+		return 4
+	}
+	if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+		return c
+	}
+	switch a := a.(type) {
+	case bool:
+		if a == b.(bool) {
+			return 0
+		} else if a {
+			return 1
+		}
+		return -1
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return cmp.Compare(a, b.(string))
+	}
+	return 0
+}
@@ -0,0 +1,175 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LoxModule is the value of an imported file. Exported names are read from
+// the module's globals, so they see later assignments made by the module.
+type LoxModule struct {
+	Name    string
+	Path    string
+	Globals *Environment
+	Exports []string
+	loaded  bool
+}
+
+func NewLoxModule(path string, globals *Environment, exports []string) *LoxModule {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &LoxModule{name, path, globals, exports, false}
+}
+
+func (m *LoxModule) Get(name *Token) any {
+	if !slices.Contains(m.Exports, name.Lexeme) {
+		panic(NewRuntimeError(name,
+			fmt.Sprintf("Module '%s' does not export '%s'.", m.Name, name.Lexeme)))
+	}
+	return m.Globals.Values[name.Lexeme]
+}
+
+func (m *LoxModule) Set(name *Token, value any) {
+	panic(NewRuntimeError(name, "Can't assign to a module's exports."))
+}
+
+func (m *LoxModule) String() string {
+	return fmt.Sprintf("<module %s>", m.Name)
+}
+
+// WithModulePath sets the directories searched for imported modules that
+// are not found next to the importing file. It defaults to the list in
+// the LOX_PATH environment variable.
+func WithModulePath(dirs ...string) Option {
+	return func(l *Lox) {
+		l.modulePath = dirs
+	}
+}
+
+// exportedNames lists the names declared by the export statements of a
+// module.
+func exportedNames(statements []Stmt) []string {
+	var names []string
+	for _, statement := range statements {
+		export, ok := statement.(*Export)
+		if !ok {
+			continue
+		}
+		switch declaration := export.Declaration.(type) {
+		case *Class:
+			names = append(names, declaration.Name.Lexeme)
+		case *Function:
+			names = append(names, declaration.Name.Lexeme)
+		case *Var:
+			names = append(names, declaration.Name.Lexeme)
+		}
+	}
+	return names
+}
+
+// importModule returns the module at path, loading and running it the
+// first time. globals are the globals of the importing code, which locate
+// the importing file.
+func (i *Interpreter) importModule(keyword *Token, path string, globals *Environment) *LoxModule {
+	file, err := i.findModule(path, filepath.Dir(i.files[globals]))
+	if err != nil {
+		panic(NewRuntimeError(keyword, err.Error()))
+	}
+
+	if module, ok := i.modules[file]; ok {
+		if !module.loaded {
+			cycle := slices.Clone(i.importing[slices.Index(i.importing, file):])
+			cycle = append(cycle, file)
+			for k, path := range cycle {
+				cycle[k] = displayPath(path)
+			}
+			panic(NewRuntimeError(keyword,
+				fmt.Sprintf("Import cycle: %s.", strings.Join(cycle, " -> "))))
+		}
+		return module
+	}
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		panic(NewRuntimeError(keyword, fmt.Sprintf("Can't read module '%s'.", path)))
+	}
+
+	reported := len(i.lox.errors)
+	statements := i.lox.parse(string(source))
+	var script *Prototype
+	if i.lox.engine == EngineVM && len(i.lox.errors) == reported {
+		script = NewCompiler(i.lox).Compile(statements)
+	}
+	if len(i.lox.errors) > reported {
+		panic(NewRuntimeError(keyword, fmt.Sprintf("Module '%s' has errors.", path)))
+	}
+
+	module := NewLoxModule(file, NewEnvironment(i.builtins), exportedNames(statements))
+	i.modules[file] = module
+	i.files[module.Globals] = file
+	i.importing = append(i.importing, file)
+	defer func() {
+		i.importing = i.importing[:len(i.importing)-1]
+		if !module.loaded {
+			delete(i.modules, file)
+		}
+	}()
+
+	i.frames = append(i.frames, frame{"module " + module.Name, "", keyword})
+	if script != nil {
+		i.vm.script(NewClosure(script, module.Globals))
+	} else {
+		previous := i.globals
+		i.globals = module.Globals
+		defer func() {
+			i.globals = previous
+		}()
+		i.executeBlock(statements, module.Globals)
+	}
+	i.popFrame()
+
+	module.loaded = true
+	return module
+}
+
+// findModule resolves an import path against the directory of the
+// importing file, then against the module path.
+func (i *Interpreter) findModule(path, dir string) (string, error) {
+	name := path
+	if filepath.Ext(name) == "" {
+		name += ".lox"
+	}
+
+	candidates := []string{name}
+	if !filepath.IsAbs(name) {
+		candidates = []string{filepath.Join(dir, name)}
+		for _, dir := range i.lox.modulePath {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err == nil && !info.IsDir() {
+			return filepath.Abs(candidate)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("Can't read module '%s'.", path)
+		}
+	}
+	return "", fmt.Errorf("Can't find module '%s'.", path)
+}
+
+// displayPath shortens an absolute path relative to the working directory
+// for messages.
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
@@ -0,0 +1,77 @@
+package lox_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+// writeFile creates the file name in dir with the given contents.
+func writeFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestModulePath(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		empty, lib := t.TempDir(), t.TempDir()
+		writeFile(t, lib, "greet.lox", `export var greeting = "from the path";`)
+
+		var out strings.Builder
+		l := newLox(engine, &out, lox.WithModulePath(empty, lib))
+		if err := l.Run(`from "greet" import greeting; print greeting;`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "from the path\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+
+		err := l.Run(`import "other" as other;`)
+		if got, want := runtimeError(t, err), "Can't find module 'other'."; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestModulePathEnvironment(t *testing.T) {
+	lib := t.TempDir()
+	writeFile(t, lib, "greet.lox", `export var greeting = "from LOX_PATH";`)
+	t.Setenv("LOX_PATH", strings.Join([]string{t.TempDir(), lib}, string(os.PathListSeparator)))
+
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		if err := l.Run(`from "greet" import greeting; print greeting;`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "from LOX_PATH\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+	})
+}
+
+func TestModulePathOrder(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		dir, lib := t.TempDir(), t.TempDir()
+		writeFile(t, lib, "greet.lox", `export var greeting = "from the path";`)
+		writeFile(t, dir, "greet.lox", `export var greeting = "next to the script";`)
+		script := writeFile(t, dir, "main.lox", `from "greet" import greeting; print greeting;`)
+
+		// Modules next to the importing file come first.
+		var out strings.Builder
+		l := newLox(engine, &out, lox.WithModulePath(lib))
+		if err := l.RunFile(script); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "next to the script\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+	})
+}
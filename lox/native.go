@@ -1,22 +1,142 @@
 package lox
 
+import (
+	"fmt"
+	"reflect"
+)
+
+// Variadic is the arity of native functions that accept any number of
+// arguments.
+const Variadic = -1
+
+type NativeFn func(interpreter *Interpreter, arguments []any) (any, error)
+
 type NativeFunc struct {
-	arity_ func() int
-	call_  func(*Interpreter, []any) any
+	Name       string
+	Capability Capability
+	min, max   int
+	params     []string
+	fn         NativeFn
+}
+
+func NewNativeFunc(name string, arity int, fn NativeFn) *NativeFunc {
+	if arity == Variadic {
+		return NewNativeFuncRange(name, 0, Variadic, fn)
+	}
+	return NewNativeFuncRange(name, arity, arity, fn)
+}
+
+// NewNativeFuncRange creates a native function that takes min to max
+// arguments, or at least min if max is Variadic.
+func NewNativeFuncRange(name string, min, max int, fn NativeFn) *NativeFunc {
+	return &NativeFunc{name, "", min, max, nil, fn}
+}
+
+// WithParameters names the parameters of n so that they can be passed by
+// keyword. Optional parameters skipped over by a keyword argument are nil.
+func (n *NativeFunc) WithParameters(names ...string) *NativeFunc {
+	n.params = names
+	return n
+}
+
+// NewGoFunc wraps an ordinary Go function as a native function. Arguments
+// are converted from Lox values to the parameter types and the results back
+// to Lox values. A trailing error result becomes a Lox runtime error.
+func NewGoFunc(name string, fn any) (*NativeFunc, error) {
+	value := reflect.ValueOf(fn)
+	ftype := value.Type()
+	if ftype.Kind() != reflect.Func {
+		return nil, fmt.Errorf("native %s: expected a function but got %s", name, ftype)
+	}
+
+	numOut := ftype.NumOut()
+	returnsError := numOut > 0 && ftype.Out(numOut-1) == errorType
+	if numOut > 2 || (numOut == 2 && !returnsError) {
+		return nil, fmt.Errorf("native %s: results must be (), (T), (error) or (T, error)", name)
+	}
+
+	min, max := ftype.NumIn(), ftype.NumIn()
+	if ftype.IsVariadic() {
+		min, max = min-1, Variadic
+	}
+
+	call := func(interpreter *Interpreter, arguments []any) (any, error) {
+		args, err := convertArguments(ftype, arguments)
+		if err != nil {
+			return nil, err
+		}
+
+		results := value.Call(args)
+		if returnsError {
+			if err, _ := results[len(results)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+			results = results[:len(results)-1]
+		}
+		if len(results) == 0 {
+			return nil, nil
+		}
+		return fromGo(results[0]), nil
+	}
+
+	return NewNativeFuncRange(name, min, max, call), nil
+}
+
+func (n *NativeFunc) Arity() (int, int) {
+	return n.min, n.max
+}
+
+func (n *NativeFunc) Parameters() []string {
+	return n.params
+}
+
+func (n *NativeFunc) Call(interpreter *Interpreter, arguments []any) any {
+	interpreter.checkCapability(n)
+	for k, argument := range arguments {
+		if argument == missing {
+			arguments[k] = nil
+		}
+	}
+	result, err := n.fn(interpreter, arguments)
+	if err != nil {
+		panic(interpreter.nativeError(err))
+	}
+	return result
+}
+
+func (n *NativeFunc) String() string {
+	return fmt.Sprintf("<native fn %s>", n.Name)
 }
 
-func NewNativeFunc(arity func() int, call func(*Interpreter, []any) any) *NativeFunc {
-	return &NativeFunc{arity, call}
+// DefineNative makes fn callable from Lox as a global function called name.
+// Pass Variadic as arity to accept any number of arguments.
+func (i *Interpreter) DefineNative(name string, arity int, fn NativeFn) {
+	i.builtins.Define(name, NewNativeFunc(name, arity, fn))
 }
 
-func (bf *NativeFunc) arity() int {
-	return bf.arity_()
+// DefineFunc makes the Go function fn callable from Lox as a global
+// function called name. See NewGoFunc for the conversion rules.
+func (i *Interpreter) DefineFunc(name string, fn any) error {
+	native, err := NewGoFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	i.builtins.Define(name, native)
+	return nil
 }
 
-func (bf *NativeFunc) call(i *Interpreter, args []any) any {
-	return bf.call_(i, args)
+// nativeError turns an error returned by a native function into a runtime
+// error located at the native's call site.
+func (i *Interpreter) nativeError(err error) *RuntimeError {
+	if runtimeError, ok := err.(*RuntimeError); ok {
+		return runtimeError
+	}
+	return NewRuntimeError(i.callSite(), err.Error())
 }
 
-func (bf *NativeFunc) String() string {
-	return "<native fn>"
+func (i *Interpreter) callSite() *Token {
+	if len(i.frames) == 0 {
+		return NewToken(EOF, "", nil, 0)
+	}
+	return i.frames[len(i.frames)-1].paren
 }
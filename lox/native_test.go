@@ -0,0 +1,127 @@
+package lox_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+// newLox creates an engine that prints to stdout and discards its error
+// reports.
+func newLox(engine lox.Engine, stdout io.Writer, options ...lox.Option) *lox.Lox {
+	options = append([]lox.Option{
+		lox.WithEngine(engine),
+		lox.WithStdout(stdout),
+		lox.WithStderr(io.Discard),
+	}, options...)
+	return lox.New(options...)
+}
+
+// forEachEngine runs test once for every engine.
+func forEachEngine(t *testing.T, test func(t *testing.T, engine lox.Engine)) {
+	for _, engine := range lox.Engines {
+		t.Run(string(engine), func(t *testing.T) {
+			test(t, engine)
+		})
+	}
+}
+
+// runtimeError returns the message of the runtime error in err.
+func runtimeError(t *testing.T, err error) string {
+	t.Helper()
+	var runtimeError *lox.RuntimeError
+	if !errors.As(err, &runtimeError) {
+		t.Fatalf("expected a runtime error but got %v", err)
+	}
+	return runtimeError.Message
+}
+
+func TestDefineNative(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		l.Interpreter().DefineNative("count", lox.Variadic, func(i *lox.Interpreter, args []any) (any, error) {
+			return float64(len(args)), nil
+		})
+		l.Interpreter().DefineNative("fail", 0, func(i *lox.Interpreter, args []any) (any, error) {
+			return nil, errors.New("Native failure.")
+		})
+
+		if err := l.Run(`print count(); print count(1, "a", nil);`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "0\n3\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+
+		err := l.Run(`fail(1);`)
+		if got, want := runtimeError(t, err), "Expected 0 arguments but got 1."; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		err = l.Run(`fail();`)
+		if got, want := runtimeError(t, err), "Native failure."; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestDefineFunc(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		i := l.Interpreter()
+		funcs := map[string]any{
+			"join": func(sep string, parts ...string) string {
+				return strings.Join(parts, sep)
+			},
+			"half": func(n int) (int, error) {
+				if n%2 != 0 {
+					return 0, fmt.Errorf("%d is odd.", n)
+				}
+				return n / 2, nil
+			},
+			"small": func(n int8) int8 { return n },
+			"byte":  func(n uint8) uint8 { return n },
+			"float": func(x float32) float32 { return x },
+		}
+		for name, fn := range funcs {
+			if err := i.DefineFunc(name, fn); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := l.Run(`print join("-"); print join("-", "a", "b"); print half(4); print small(-128);`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "\na-b\n2\n-128\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+
+		tests := []struct {
+			source string
+			want   string
+		}{
+			{`join();`, "Expected at least 1 arguments but got 0."},
+			{`half(3);`, "3 is odd."},
+			{`half("4");`, "Argument 1: Expected int but got string."},
+			{`half(1.5);`, "Argument 1: Expected an integer but got 1.5."},
+			{`join("-", "a", 1);`, "Argument 3: Expected string but got number."},
+			{`small(300);`, "Argument 1: Expected int8 but got 300."},
+			{`small(-129);`, "Argument 1: Expected int8 but got -129."},
+			{`byte(256);`, "Argument 1: Expected uint8 but got 256."},
+			{`byte(-1);`, "Argument 1: Expected a non-negative integer but got -1."},
+			{`half(2 ** 64);`, "Argument 1: Expected int but got 1.8446744073709552e+19."},
+			{`float(2 ** 200);`, "Argument 1: Expected float32 but got 1.6069380442589903e+60."},
+		}
+		for _, test := range tests {
+			err := l.Run(test.source)
+			if got := runtimeError(t, err); got != test.want {
+				t.Errorf("%s: got %q, want %q", test.source, got, test.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// The operations in this file define the semantics of the language and are
+// shared by the tree-walking interpreter and the bytecode VM.
+
+// operatorMethods are the special methods that overload binary operators
+// for instances of a class. == and != use __eq__.
+var operatorMethods = map[TokenType]string{
+	PLUS:          "__add__",
+	MINUS:         "__sub__",
+	STAR:          "__mul__",
+	SLASH:         "__div__",
+	PERCENT:       "__mod__",
+	TILDE_SLASH:   "__intdiv__",
+	STAR_STAR:     "__pow__",
+	LESS:          "__lt__",
+	LESS_EQUAL:    "__le__",
+	GREATER:       "__gt__",
+	GREATER_EQUAL: "__ge__",
+}
+
+// specialMethod returns the method name of object's class bound to object,
+// or nil if object is not an instance or its class has no such method.
+// Fields don't count, so a special method can't be set on one instance.
+func specialMethod(object any, name string) Method {
+	instance, ok := object.(*Instance)
+	if !ok {
+		return nil
+	}
+	if method := instance.class.FindMethod(name); method != nil {
+		return method.Bind(instance)
+	}
+	return nil
+}
+
+// binaryOp applies a binary operator. An instance on the left whose class
+// has the operator's special method handles the operator itself.
+func (i *Interpreter) binaryOp(operator *Token, left, right any) any {
+	if method := specialMethod(left, operatorMethods[operator.Type]); method != nil {
+		return i.callValue(method, []any{right}, operator)
+	}
+
+	switch operator.Type {
+	case GREATER:
+		i.checkNumberOperands(operator, left, right)
+		return left.(float64) > right.(float64)
+	case GREATER_EQUAL:
+		i.checkNumberOperands(operator, left, right)
+		return left.(float64) >= right.(float64)
+	case LESS:
+		i.checkNumberOperands(operator, left, right)
+		return left.(float64) < right.(float64)
+	case LESS_EQUAL:
+		i.checkNumberOperands(operator, left, right)
+		return left.(float64) <= right.(float64)
+	case MINUS:
+		i.checkNumberOperands(operator, left, right)
+		return left.(float64) - right.(float64)
+	case BANG_EQUAL:
+		return !i.isEqual(left, right, operator)
+	case EQUAL_EQUAL:
+		return i.isEqual(left, right, operator)
+	case PLUS:
+		lval, lok := left.(float64)
+		rval, rok := right.(float64)
+		if lok && rok {
+			return lval + rval
+		}
+
+		lstr, lok := left.(string)
+		rstr, rok := right.(string)
+		if lok && rok {
+			return lstr + rstr
+		}
+
+		panic(NewRuntimeError(
+			operator,
+			"Operands must be two numbers or two strings.",
+		))
+	case SLASH, PERCENT, TILDE_SLASH:
+		i.checkNumberOperands(operator, left, right)
+		return i.divide(operator, left.(float64), right.(float64))
+	case STAR:
+		i.checkNumberOperands(operator, left, right)
+		return left.(float64) * right.(float64)
+	case STAR_STAR:
+		i.checkNumberOperands(operator, left, right)
+		return math.Pow(left.(float64), right.(float64))
+	}
+	return nil
+}
+
+// ZeroDivision selects what dividing by zero does.
+type ZeroDivision int
+
+const (
+	// ZeroDivisionIEEE gives the IEEE 754 results: infinities for
+	// division and NaN for modulo and 0 / 0.
+	ZeroDivisionIEEE ZeroDivision = iota
+	// ZeroDivisionError raises a runtime error.
+	ZeroDivisionError
+)
+
+// WithZeroDivision sets how division, integer division and modulo by zero
+// behave. It defaults to ZeroDivisionIEEE.
+func WithZeroDivision(mode ZeroDivision) Option {
+	return func(l *Lox) {
+		l.zeroDivision = mode
+	}
+}
+
+// divide applies /, % or ~/. Integer division and modulo truncate towards
+// zero, so x == (x ~/ y) * y + x % y.
+func (i *Interpreter) divide(operator *Token, x, y float64) float64 {
+	if y == 0 && i.lox.zeroDivision == ZeroDivisionError {
+		panic(NewRuntimeError(operator, "Division by zero."))
+	}
+	switch operator.Type {
+	case PERCENT:
+		return math.Mod(x, y)
+	case TILDE_SLASH:
+		return math.Trunc(x / y)
+	}
+	return x / y
+}
+
+func (i *Interpreter) unaryOp(operator *Token, right any) any {
+	switch operator.Type {
+	case MINUS:
+		if method := specialMethod(right, "__neg__"); method != nil {
+			return i.callValue(method, nil, operator)
+		}
+		i.checkNumberOperand(operator, right)
+		return -right.(float64)
+	case BANG:
+		return !i.isTruthy(right)
+	}
+	return nil
+}
+
+// callValue calls callee with arguments evaluated at the call site paren.
+func (i *Interpreter) callValue(callee any, arguments []any, paren *Token) any {
+	function, ok := callee.(Callable)
+	if !ok {
+		panic(NewRuntimeError(
+			paren, "Can only call functions and classes.",
+		))
+	}
+
+	i.checkArity(function, len(arguments), paren)
+
+	i.pushFrame(function, paren)
+	result := function.Call(i, arguments)
+	i.popFrame()
+	return result
+}
+
+func (i *Interpreter) checkArity(function Callable, count int, paren *Token) {
+	if message := arityError(function, count); message != "" {
+		panic(NewRuntimeError(paren, message))
+	}
+}
+
+// arityError describes why function can't be called with count
+// arguments, or is empty if it can.
+func arityError(function Callable, count int) string {
+	min, max := function.Arity()
+	switch {
+	case count >= min && (max == Variadic || count <= max):
+		return ""
+	case min == max:
+		return fmt.Sprintf("Expected %d arguments but got %d.", min, count)
+	case max == Variadic:
+		return fmt.Sprintf("Expected at least %d arguments but got %d.", min, count)
+	}
+	return fmt.Sprintf("Expected %d to %d arguments but got %d.", min, max, count)
+}
+
+// missingArgument is the type of missing.
+type missingArgument struct{}
+
+// missing takes the place of the parameters skipped over by keyword
+// arguments until the callee fills in their defaults.
+var missing = missingArgument{}
+
+// bindArguments moves the trailing keyword arguments of a call, named by
+// names, to the positions of the callee's parameters.
+func (i *Interpreter) bindArguments(callee any, arguments []any, names []string, paren *Token) []any {
+	if _, ok := callee.(Callable); !ok {
+		return arguments
+	}
+	var parameters []string
+	if function, ok := callee.(KeywordCallable); ok {
+		parameters = function.Parameters()
+	}
+
+	positional := len(arguments) - len(names)
+	bound := slices.Clone(arguments[:positional])
+	for k, name := range names {
+		index := slices.Index(parameters, name)
+		if index < 0 {
+			panic(NewRuntimeError(paren,
+				fmt.Sprintf("Unexpected keyword argument '%s'.", name)))
+		}
+		if index < len(bound) && bound[index] != missing {
+			panic(NewRuntimeError(paren,
+				fmt.Sprintf("Argument '%s' passed more than once.", name)))
+		}
+		for len(bound) <= index {
+			bound = append(bound, missing)
+		}
+		bound[index] = arguments[positional+k]
+	}
+
+	min, _ := callee.(Callable).Arity()
+	for k := positional; k < min; k++ {
+		if k >= len(bound) || bound[k] == missing {
+			panic(NewRuntimeError(paren,
+				fmt.Sprintf("Missing argument for parameter '%s'.", parameters[k])))
+		}
+	}
+	return bound
+}
+
+func keywordNames(names []*Token) []string {
+	keywords := make([]string, len(names))
+	for k, name := range names {
+		keywords[k] = name.Lexeme
+	}
+	return keywords
+}
+
+func (i *Interpreter) getProperty(object any, name *Token) any {
+	if val, ok := object.(Object); ok {
+		return i.runGetter(val.Get(name), name)
+	}
+	panic(NewRuntimeError(name,
+		"Only instances have properties."))
+}
+
+func (i *Interpreter) setProperty(object any, name *Token, value any) {
+	if val, ok := object.(Object); ok {
+		val.Set(name, value)
+		return
+	}
+	panic(NewRuntimeError(name,
+		"Only instances have fields."))
+}
+
+// matches reports whether a match statement's value matches a case
+// pattern. A class matches its instances and those of its subclasses,
+// without asking __eq__; any other pattern matches equal values.
+func (i *Interpreter) matches(value, pattern any, keyword *Token) bool {
+	if class, ok := pattern.(*LoxClass); ok {
+		if instance, ok := value.(*Instance); ok {
+			for c := instance.class; c != nil; c = c.Superclass {
+				if c == class {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return i.isEqual(value, pattern, keyword)
+}
+
+// runGetter calls value if it is a getter read as the property name, and
+// returns other values unchanged.
+func (i *Interpreter) runGetter(value any, name *Token) any {
+	if method, ok := value.(Method); ok && method.IsGetter() {
+		return i.callValue(method, nil, name)
+	}
+	return value
+}
+
+// superMethod looks up a method on superclass and binds it to object. In
+// class methods object is the class, and the superclass's class methods
+// are searched instead.
+func (i *Interpreter) superMethod(superclass *LoxClass, object any, name *Token) any {
+	var method Method
+	if _, ok := object.(*LoxClass); ok {
+		method = superclass.FindClassMethod(name.Lexeme)
+	} else {
+		method = superclass.FindMethod(name.Lexeme)
+	}
+	if method == nil {
+		panic(NewRuntimeError(
+			name,
+			fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+		))
+	}
+	return i.runGetter(method.Bind(object), name)
+}
+
+// getIndex evaluates object[index], where bracket locates the expression.
+// Instances are indexed by the __index__ method of their class.
+func (i *Interpreter) getIndex(object, index any, bracket *Token) any {
+	if method := specialMethod(object, "__index__"); method != nil {
+		return i.callValue(method, []any{index}, bracket)
+	}
+	if list, ok := object.(*LoxList); ok {
+		k, err := listIndex(index, len(list.Elements))
+		if err != nil {
+			panic(NewRuntimeError(bracket, err.Error()))
+		}
+		return list.Elements[k]
+	}
+	if m, ok := object.(*LoxMap); ok {
+		value, ok, err := m.Lookup(index)
+		if err != nil {
+			panic(NewRuntimeError(bracket, err.Error()))
+		}
+		if !ok {
+			panic(NewRuntimeError(bracket,
+				fmt.Sprintf("Undefined key '%s'.", i.stringify(index, bracket))))
+		}
+		return value
+	}
+	panic(NewRuntimeError(bracket, "Only lists and maps can be indexed."))
+}
+
+// setIndex assigns object[index], or calls __setindex__ for instances.
+func (i *Interpreter) setIndex(object, index, value any, bracket *Token) {
+	if method := specialMethod(object, "__setindex__"); method != nil {
+		i.callValue(method, []any{index, value}, bracket)
+		return
+	}
+	if list, ok := object.(*LoxList); ok {
+		k, err := listIndex(index, len(list.Elements))
+		if err == nil {
+			err = list.checkWritable()
+		}
+		if err != nil {
+			panic(NewRuntimeError(bracket, err.Error()))
+		}
+		list.Elements[k] = value
+		return
+	}
+	if m, ok := object.(*LoxMap); ok {
+		if err := m.Put(index, value); err != nil {
+			panic(NewRuntimeError(bracket, err.Error()))
+		}
+		return
+	}
+	panic(NewRuntimeError(bracket, "Only lists and maps can be indexed."))
+}
+
+// newMap builds a map literal from alternating keys and values.
+func (i *Interpreter) newMap(entries []any, brace *Token) *LoxMap {
+	m := NewLoxMap()
+	for k := 0; k < len(entries); k += 2 {
+		if err := m.Put(entries[k], entries[k+1]); err != nil {
+			panic(NewRuntimeError(brace, err.Error()))
+		}
+	}
+	return m
+}
@@ -0,0 +1,29 @@
+package lox_test
+
+import (
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+func TestZeroDivision(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out strings.Builder
+		l := newLox(engine, &out)
+		if err := l.Run(`print 1 / 0; print 1 % 0; print 1 ~/ 0;`); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := out.String(), "+Inf\nNaN\n+Inf\n"; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+
+		l = newLox(engine, &out, lox.WithZeroDivision(lox.ZeroDivisionError))
+		for _, source := range []string{`1 / 0;`, `1 % 0;`, `1 ~/ 0;`, `var x = 1; x /= 0;`} {
+			err := l.Run(source)
+			if got, want := runtimeError(t, err), "Division by zero."; got != want {
+				t.Errorf("%s: got %q, want %q", source, got, want)
+			}
+		}
+	})
+}
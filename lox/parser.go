@@ -3,12 +3,15 @@ package lox
 import "fmt"
 
 type Parser struct {
+	lox     *Lox
 	tokens  []*Token
 	current int
+	// generator is set by a yield statement in the function being parsed.
+	generator bool
 }
 
-func NewParser(tokens []*Token) *Parser {
-	return &Parser{tokens, 0}
+func NewParser(lox *Lox, tokens []*Token) *Parser {
+	return &Parser{lox, tokens, 0, false}
 }
 
 func (p *Parser) Parse() []Stmt {
@@ -26,15 +29,32 @@ func (p *Parser) expression() Expr {
 func (p *Parser) declaration() Stmt {
 	defer func() {
 		if r := recover(); r != nil {
-			LoxInstance.Report(r.(error))
+			p.lox.Report(r.(error))
 			p.synchronize()
 		}
 	}()
 
+	if p.match(EXPORT) {
+		return p.exportDeclaration()
+	}
+	if p.match(IMPORT) {
+		return p.importDeclaration()
+	}
+	if p.checkContextual("from") && p.tokens[p.current+1].Type == STRING {
+		p.advance()
+		return p.fromImportDeclaration()
+	}
+	return p.namedDeclaration()
+}
+
+// namedDeclaration parses a class, function or variable declaration, or
+// a statement.
+func (p *Parser) namedDeclaration() Stmt {
 	if p.match(CLASS) {
 		return p.classDeclaration()
 	}
-	if p.match(FUN) {
+	if p.check(FUN) && p.tokens[p.current+1].Type != LEFT_PAREN {
+		p.advance()
 		return p.function("function")
 	}
 	if p.match(VAR) {
@@ -43,6 +63,50 @@ func (p *Parser) declaration() Stmt {
 	return p.statement()
 }
 
+func (p *Parser) exportDeclaration() Stmt {
+	keyword := p.previous()
+	if !p.check(CLASS) && !p.check(FUN) && !p.check(VAR) {
+		panic(NewParseError(p.peek(), "Expect declaration after 'export'."))
+	}
+	return NewExport(keyword, p.namedDeclaration())
+}
+
+func (p *Parser) importDeclaration() Stmt {
+	keyword := p.previous()
+	path := p.consume(STRING, "Expect module path after 'import'.")
+	if !p.matchContextual("as") {
+		panic(NewParseError(p.peek(), "Expect 'as' after module path."))
+	}
+	alias := p.consume(IDENTIFIER, "Expect module name after 'as'.")
+	p.consume(SEMICOLON, "Expect ';' after import.")
+	return NewImport(keyword, path, alias, nil)
+}
+
+func (p *Parser) fromImportDeclaration() Stmt {
+	path := p.consume(STRING, "Expect module path after 'from'.")
+	keyword := p.consume(IMPORT, "Expect 'import' after module path.")
+	names := []*Token{p.consume(IDENTIFIER, "Expect name to import.")}
+	for p.match(COMMA) {
+		names = append(names, p.consume(IDENTIFIER, "Expect name to import."))
+	}
+	p.consume(SEMICOLON, "Expect ';' after import.")
+	return NewImport(keyword, path, nil, names)
+}
+
+// checkContextual reports whether the next token is the identifier word,
+// which has a meaning only in some positions.
+func (p *Parser) checkContextual(word string) bool {
+	return p.check(IDENTIFIER) && p.peek().Lexeme == word
+}
+
+func (p *Parser) matchContextual(word string) bool {
+	if p.checkContextual(word) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
 func (p *Parser) classDeclaration() Stmt {
 	name := p.consume(IDENTIFIER, "Expect class name.")
 
@@ -55,14 +119,23 @@ func (p *Parser) classDeclaration() Stmt {
 	p.consume(LEFT_BRACE, "Expect '{' before class body.")
 
 	methods := make([]*Function, 0)
+	classMethods := make([]*Function, 0)
+	fields := make([]*Var, 0)
 	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
-		function, _ := p.function("method").(*Function)
-		methods = append(methods, function)
+		if p.match(VAR) {
+			fields = append(fields, p.varDeclaration().(*Var))
+		} else if p.match(CLASS) {
+			function, _ := p.function("method").(*Function)
+			classMethods = append(classMethods, function)
+		} else {
+			function, _ := p.function("method").(*Function)
+			methods = append(methods, function)
+		}
 	}
 
 	p.consume(RIGHT_BRACE, "Expect '}' after class body.")
 
-	return NewClass(name, superclass, methods)
+	return NewClass(name, superclass, methods, classMethods, fields)
 }
 
 func (p *Parser) statement() Stmt {
@@ -70,21 +143,51 @@ func (p *Parser) statement() Stmt {
 		return p.forStatement()
 	} else if p.match(IF) {
 		return p.ifStatement()
+	} else if p.match(MATCH) {
+		return p.matchStatement()
 	} else if p.match(PRINT) {
 		return p.printStatement()
 	} else if p.match(RETURN) {
 		return p.returnStatement()
+	} else if p.match(THROW) {
+		return p.throwStatement()
+	} else if p.match(TRY) {
+		return p.tryStatement()
 	} else if p.match(WHILE) {
 		return p.whileStatement()
-	} else if p.match(LEFT_BRACE) {
+	} else if p.match(YIELD) {
+		return p.yieldStatement()
+	} else if p.match(BREAK, CONTINUE) {
+		return p.loopControlStatement()
+	} else if p.check(LEFT_BRACE) && !p.isMapLiteral() {
+		p.advance()
 		return NewBlock(p.block())
 	}
 	return p.expressionStatement()
 }
 
+// isMapLiteral tells a map literal at the start of a statement from a
+// block by looking for a key followed by a colon.
+func (p *Parser) isMapLiteral() bool {
+	if p.current+2 >= len(p.tokens) {
+		return false
+	}
+	switch p.tokens[p.current+1].Type {
+	case STRING, NUMBER, TRUE, FALSE, NIL, IDENTIFIER:
+		return p.tokens[p.current+2].Type == COLON
+	}
+	return false
+}
+
 func (p *Parser) forStatement() Stmt {
+	keyword := p.previous()
 	p.consume(LEFT_PAREN, "Expect '(' after 'for'.")
 
+	if p.check(VAR) && p.tokens[p.current+1].Type == IDENTIFIER &&
+		p.tokens[p.current+2].Type == IDENTIFIER && p.tokens[p.current+2].Lexeme == "in" {
+		return p.forInStatement(keyword)
+	}
+
 	var initializer Stmt
 	if p.match(SEMICOLON) {
 	} else if p.match(VAR) {
@@ -107,17 +210,11 @@ func (p *Parser) forStatement() Stmt {
 
 	body := p.statement()
 
-	if increment != nil {
-		body = NewBlock([]Stmt{
-			body, NewExpression(increment),
-		})
-	}
-
 	if condition == nil {
 		condition = NewLiteral(true)
 	}
 
-	body = NewWhile(condition, body)
+	body = NewWhile(keyword, condition, body, increment)
 
 	if initializer != nil {
 		body = NewBlock([]Stmt{
@@ -128,7 +225,20 @@ func (p *Parser) forStatement() Stmt {
 	return body
 }
 
+// forInStatement parses 'var name in iterable) body' after the '(' of a
+// for statement.
+func (p *Parser) forInStatement(keyword *Token) Stmt {
+	p.advance()
+	name := p.advance()
+	p.advance()
+	iterable := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after for-in clause.")
+	body := p.statement()
+	return NewForIn(keyword, name, iterable, body)
+}
+
 func (p *Parser) ifStatement() Stmt {
+	keyword := p.previous()
 	p.consume(LEFT_PAREN, "Expect '(' after 'if'.")
 	condition := p.expression()
 	p.consume(RIGHT_PAREN, "Expect ')' after if condition.")
@@ -139,13 +249,55 @@ func (p *Parser) ifStatement() Stmt {
 		elseBranch = p.statement()
 	}
 
-	return NewIf(condition, thenBranch, elseBranch)
+	return NewIf(keyword, condition, thenBranch, elseBranch)
+}
+
+func (p *Parser) matchStatement() Stmt {
+	keyword := p.previous()
+	p.consume(LEFT_PAREN, "Expect '(' after 'match'.")
+	value := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after match value.")
+	p.consume(LEFT_BRACE, "Expect '{' before match cases.")
+
+	var patterns [][]Expr
+	var guards []Expr
+	var bodies []Stmt
+	var otherwise Stmt
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		if otherwise != nil {
+			panic(NewParseError(p.peek(), "Expect '}' after default case."))
+		}
+		if p.match(DEFAULT) {
+			p.consume(ARROW, "Expect '=>' after 'default'.")
+			otherwise = p.statement()
+			continue
+		}
+
+		p.consume(CASE, "Expect 'case' or 'default' in match.")
+		arm := []Expr{p.expression()}
+		for p.match(COMMA) {
+			arm = append(arm, p.expression())
+		}
+		var guard Expr
+		if p.match(IF) {
+			guard = p.expression()
+		}
+		p.consume(ARROW, "Expect '=>' after case patterns.")
+
+		patterns = append(patterns, arm)
+		guards = append(guards, guard)
+		bodies = append(bodies, p.statement())
+	}
+
+	p.consume(RIGHT_BRACE, "Expect '}' after match cases.")
+	return NewMatch(keyword, value, patterns, guards, bodies, otherwise)
 }
 
 func (p *Parser) printStatement() Stmt {
+	keyword := p.previous()
 	value := p.expression()
 	p.consume(SEMICOLON, "Expect ';' after value.")
-	return NewPrint(value)
+	return NewPrint(keyword, value)
 }
 
 func (p *Parser) returnStatement() Stmt {
@@ -159,6 +311,58 @@ func (p *Parser) returnStatement() Stmt {
 	return NewReturn(keyword, value)
 }
 
+func (p *Parser) yieldStatement() Stmt {
+	keyword := p.previous()
+	var value Expr
+	if !p.check(SEMICOLON) {
+		value = p.expression()
+	}
+
+	p.consume(SEMICOLON, "Expect ';' after yield value.")
+	p.generator = true
+	return NewYield(keyword, value)
+}
+
+func (p *Parser) throwStatement() Stmt {
+	keyword := p.previous()
+	value := p.expression()
+	p.consume(SEMICOLON, "Expect ';' after thrown value.")
+	return NewThrow(keyword, value)
+}
+
+func (p *Parser) tryStatement() Stmt {
+	keyword := p.previous()
+	p.consume(LEFT_BRACE, "Expect '{' after 'try'.")
+	body := p.block()
+
+	var name *Token
+	var handler, finally []Stmt
+	if p.match(CATCH) {
+		p.consume(LEFT_PAREN, "Expect '(' after 'catch'.")
+		name = p.consume(IDENTIFIER, "Expect error variable name.")
+		p.consume(RIGHT_PAREN, "Expect ')' after error variable.")
+		p.consume(LEFT_BRACE, "Expect '{' before catch body.")
+		handler = p.block()
+	}
+	if p.match(FINALLY) {
+		p.consume(LEFT_BRACE, "Expect '{' after 'finally'.")
+		finally = p.block()
+	}
+	if name == nil && finally == nil {
+		panic(NewParseError(p.peek(), "Expect 'catch' or 'finally' after try block."))
+	}
+	return NewTry(keyword, body, name, handler, finally)
+}
+
+func (p *Parser) loopControlStatement() Stmt {
+	keyword := p.previous()
+	p.consume(SEMICOLON, fmt.Sprintf("Expect ';' after '%s'.", keyword.Lexeme))
+	if keyword.Type == BREAK {
+		return NewBreak(keyword)
+	}
+	return NewContinue(keyword)
+}
+
 func (p *Parser) varDeclaration() Stmt {
 	name := p.consume(IDENTIFIER, "Expect variable name.")
 
@@ -172,12 +376,13 @@ func (p *Parser) varDeclaration() Stmt {
 }
 
 func (p *Parser) whileStatement() Stmt {
+	keyword := p.previous()
 	p.consume(LEFT_PAREN, "Expect '(' after 'while'.")
 	condition := p.expression()
 	p.consume(RIGHT_PAREN, "Expect ')' after condition.")
 	body := p.statement()
 
-	return NewWhile(condition, body)
+	return NewWhile(keyword, condition, body, nil)
 }
 
 func (p *Parser) expressionStatement() Stmt {
@@ -188,28 +393,170 @@ func (p *Parser) expressionStatement() Stmt {
 
 func (p *Parser) function(kind string) Stmt {
 	name := p.consume(IDENTIFIER, fmt.Sprintf("Expect %s name.", kind))
-	p.consume(LEFT_PAREN, fmt.Sprintf("Expect '(' after %s name.", kind))
+
+	// A method without a parameter list is a getter, marked by nil
+	// parameters.
+	var parameters []*Token
+	var defaults []Expr
+	var rest *Token
+	if kind != "method" || !p.check(LEFT_BRACE) {
+		p.consume(LEFT_PAREN, fmt.Sprintf("Expect '(' after %s name.", kind))
+		parameters, defaults, rest = p.parameters()
+	}
+
+	p.consume(LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body.", kind))
+	body, generator := p.functionBody(p.block)
+	return NewFunction(name, parameters, defaults, rest, body, generator)
+}
+
+// lambda parses an anonymous function expression after its 'fun'.
+func (p *Parser) lambda() Expr {
+	keyword := p.previous()
+	p.consume(LEFT_PAREN, "Expect '(' after 'fun'.")
+	parameters, defaults, rest := p.parameters()
+
+	p.consume(LEFT_BRACE, "Expect '{' before function body.")
+	body, generator := p.functionBody(p.block)
+	function := NewFunction(nil, parameters, defaults, rest, body, generator).(*Function)
+	return NewLambda(keyword, function)
+}
+
+// arrow parses '(params) => body', where body is a block or a single
+// expression whose value is returned.
+func (p *Parser) arrow() Expr {
+	p.consume(LEFT_PAREN, "Expect '(' before parameters.")
+	parameters, defaults, rest := p.parameters()
+	arrow := p.consume(ARROW, "Expect '=>' after parameters.")
+
+	body, generator := p.functionBody(func() []Stmt {
+		if p.match(LEFT_BRACE) {
+			return p.block()
+		}
+		return []Stmt{NewReturn(arrow, p.expression())}
+	})
+	function := NewFunction(nil, parameters, defaults, rest, body, generator).(*Function)
+	return NewLambda(arrow, function)
+}
+
+// functionBody parses a function body with parse and reports whether it
+// contains a yield statement, which makes the function a generator.
+func (p *Parser) functionBody(parse func() []Stmt) ([]Stmt, bool) {
+	enclosing := p.generator
+	p.generator = false
+	body := parse()
+	generator := p.generator
+	p.generator = enclosing
+	return body, generator
+}
+
+// isArrow looks ahead for a parenthesized parameter list followed by '=>'.
+// Default values are skipped up to the next ',' or ')' outside brackets.
+func (p *Parser) isArrow() bool {
+	k := p.current + 1
+	for p.tokens[k].Type != RIGHT_PAREN {
+		if p.tokens[k].Type == ELLIPSIS {
+			k++
+		}
+		if p.tokens[k].Type != IDENTIFIER {
+			return false
+		}
+		k++
+		if p.tokens[k].Type == EQUAL {
+			k++
+			for depth := 0; depth > 0 ||
+				(p.tokens[k].Type != COMMA && p.tokens[k].Type != RIGHT_PAREN); k++ {
+				switch p.tokens[k].Type {
+				case LEFT_PAREN, LEFT_BRACKET, LEFT_BRACE:
+					depth++
+				case RIGHT_PAREN, RIGHT_BRACKET, RIGHT_BRACE:
+					depth--
+				case EOF:
+					return false
+				}
+			}
+		}
+		if p.tokens[k].Type == COMMA {
+			k++
+		} else if p.tokens[k].Type != RIGHT_PAREN {
+			return false
+		}
+	}
+	return p.tokens[k+1].Type == ARROW
+}
+
+// interpolation parses a string with embedded expressions into the
+// concatenation of its parts.
+func (p *Parser) interpolation() Expr {
+	var expr Expr
+	add := func(part Expr) {
+		if expr == nil {
+			expr = part
+		} else {
+			plus := NewToken(PLUS, "+", nil, p.previous().Line)
+			expr = NewBinary(expr, plus, part)
+		}
+	}
+
+	for {
+		start := p.previous()
+		if start.Literal != "" {
+			add(NewLiteral(start.Literal))
+		}
+		add(NewStringify(start, p.expression()))
+
+		if p.match(INTERPOLATION) {
+			continue
+		}
+
+		end := p.consume(STRING, "Expect '}' after interpolated expression.")
+		if end.Literal != "" {
+			add(NewLiteral(end.Literal))
+		}
+		return expr
+	}
+}
+
+// parameters parses a parameter list up to and including the ')'.
+// Parameters may have default values, and the last one may be a rest
+// parameter collecting the remaining arguments. defaults has a nil entry
+// for each parameter without one.
+func (p *Parser) parameters() ([]*Token, []Expr, *Token) {
 	parameters := make([]*Token, 0)
+	defaults := make([]Expr, 0)
+	var rest *Token
 	if !p.check(RIGHT_PAREN) {
-		parameters = append(parameters,
-			p.consume(IDENTIFIER, "Expect parameter name."),
-		)
-		for p.match(COMMA) {
+		for {
 			if len(parameters) >= 255 {
-				panic(NewRuntimeError(
+				panic(NewParseError(
 					p.peek(), "Can't have more than 255 parameters.",
 				))
 			}
-			parameters = append(parameters,
-				p.consume(IDENTIFIER, "Expect parameter name."),
-			)
+			if p.match(ELLIPSIS) {
+				rest = p.consume(IDENTIFIER, "Expect parameter name.")
+				if p.check(COMMA) {
+					panic(NewParseError(p.peek(), "Rest parameter must be last."))
+				}
+				break
+			}
+
+			parameter := p.consume(IDENTIFIER, "Expect parameter name.")
+			var value Expr
+			if p.match(EQUAL) {
+				value = p.expression()
+			} else if len(defaults) > 0 && defaults[len(defaults)-1] != nil {
+				panic(NewParseError(parameter,
+					"Parameter without a default can't follow one with a default."))
+			}
+			parameters = append(parameters, parameter)
+			defaults = append(defaults, value)
+
+			if !p.match(COMMA) {
+				break
+			}
 		}
 	}
 	p.consume(RIGHT_PAREN, "Expect ')' after parameters.")
-
-	p.consume(LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body.", kind))
-	body := p.block()
-	return NewFunction(name, parameters, body)
+	return parameters, defaults, rest
 }
 
 func (p *Parser) block() []Stmt {
@@ -224,7 +571,7 @@ func (p *Parser) block() []Stmt {
 }
 
 func (p *Parser) assignment() Expr {
-	expr := p.or()
+	expr := p.conditional()
 
 	if p.match(EQUAL) {
 		equals := p.previous()
@@ -236,9 +583,61 @@ func (p *Parser) assignment() Expr {
 		} else if val, ok := expr.(*Get); ok {
 			get := val
 			return NewSet(get.Object, get.Name, value)
+		} else if index, ok := expr.(*Index); ok {
+			return NewSetIndex(index.Object, index.Bracket, index.Index, value)
 		}
 		panic(NewParseError(equals, "Invalid assignment target."))
 	}
+
+	if p.match(PLUS_EQUAL, MINUS_EQUAL, STAR_EQUAL, SLASH_EQUAL) {
+		operator := p.previous()
+		value := p.assignment()
+		return p.compound(expr, operator, value, false)
+	}
+	return expr
+}
+
+func isAssignable(expr Expr) bool {
+	switch expr.(type) {
+	case *Variable, *Get, *Index:
+		return true
+	}
+	return false
+}
+
+// compoundOperators map compound assignment and increment operators to
+// the binary operator they apply.
+var compoundOperators = map[TokenType]TokenType{
+	PLUS_EQUAL:  PLUS,
+	MINUS_EQUAL: MINUS,
+	STAR_EQUAL:  STAR,
+	SLASH_EQUAL: SLASH,
+	PLUS_PLUS:   PLUS,
+	MINUS_MINUS: MINUS,
+}
+
+// compound builds a compound assignment to target. The operator of the
+// node is the binary operator it applies, keeping the source lexeme.
+func (p *Parser) compound(target Expr, operator *Token, value Expr, postfix bool) Expr {
+	if !isAssignable(target) {
+		panic(NewParseError(operator, "Invalid assignment target."))
+	}
+	binary := NewToken(compoundOperators[operator.Type], operator.Lexeme, nil, operator.Line)
+	return NewCompound(target, binary, value, postfix)
+}
+
+// conditional parses 'condition ? then : else', which nests to the right.
+func (p *Parser) conditional() Expr {
+	expr := p.or()
+
+	if p.match(QUESTION) {
+		question := p.previous()
+		thenBranch := p.expression()
+		p.consume(COLON, "Expect ':' after then branch of conditional expression.")
+		elseBranch := p.conditional()
+		return NewConditional(expr, question, thenBranch, elseBranch)
+	}
+
 	return expr
 }
 
@@ -305,7 +704,7 @@ func (p *Parser) term() Expr {
 func (p *Parser) factor() Expr {
 	expr := p.unary()
 
-	for p.match(SLASH, STAR) {
+	for p.match(SLASH, STAR, PERCENT, TILDE_SLASH) {
 		operator := p.previous()
 		right := p.unary()
 		expr = NewBinary(expr, operator, right)
@@ -320,24 +719,72 @@ func (p *Parser) unary() Expr {
 		right := p.unary()
 		return NewUnary(operator, right)
 	}
-	return p.call()
+	if p.match(PLUS_PLUS, MINUS_MINUS) {
+		operator := p.previous()
+		target := p.unary()
+		if operator.Type == MINUS_MINUS && !isAssignable(target) {
+			// '--' before a value that can't be assigned is a double
+			// negation, as in '--(3)'.
+			minus := NewToken(MINUS, "-", nil, operator.Line)
+			return NewUnary(minus, NewUnary(minus, target))
+		}
+		return p.compound(target, operator, NewLiteral(1.0), false)
+	}
+	return p.power()
+}
+
+// power parses exponentiation, which nests to the right and binds tighter
+// than a unary operator on its left: -2 ** 2 is -(2 ** 2).
+func (p *Parser) power() Expr {
+	expr := p.postfix()
+
+	if p.match(STAR_STAR) {
+		operator := p.previous()
+		right := p.unary()
+		return NewBinary(expr, operator, right)
+	}
+
+	return expr
+}
+
+func (p *Parser) postfix() Expr {
+	expr := p.call()
+
+	if p.match(PLUS_PLUS, MINUS_MINUS) {
+		return p.compound(expr, p.previous(), NewLiteral(1.0), true)
+	}
+
+	return expr
 }
 
+// finishCall parses the arguments of a call. Keyword arguments, written
+// 'name: value', come after the positional ones.
 func (p *Parser) finishCall(callee Expr) Expr {
 	arguments := make([]Expr, 0)
+	var names []*Token
 	if !p.check(RIGHT_PAREN) {
-		arguments = append(arguments, p.expression())
-		for p.match(COMMA) {
+		for {
 			if len(arguments) >= 255 {
 				panic(NewParseError(p.peek(), "Can't have more than 255 arguments."))
 			}
+			if p.check(IDENTIFIER) && p.tokens[p.current+1].Type == COLON {
+				names = append(names, p.advance())
+				p.advance()
+			} else if len(names) > 0 {
+				panic(NewParseError(p.peek(),
+					"Positional argument can't follow a keyword argument."))
+			}
 			arguments = append(arguments, p.expression())
+
+			if !p.match(COMMA) {
+				break
+			}
 		}
 	}
 
 	paren := p.consume(RIGHT_PAREN, "Expect ')' after arguments.")
 
-	return NewCall(callee, paren, arguments)
+	return NewCall(callee, paren, arguments, names)
 }
 
 func (p *Parser) call() Expr {
@@ -350,6 +797,11 @@ func (p *Parser) call() Expr {
 			name := p.consume(IDENTIFIER,
 				"Expect property name after '.'")
 			expr = NewGet(expr, name)
+		} else if p.match(LEFT_BRACKET) {
+			bracket := p.previous()
+			index := p.expression()
+			p.consume(RIGHT_BRACKET, "Expect ']' after index.")
+			expr = NewIndex(expr, bracket, index)
 		} else {
 			break
 		}
@@ -375,6 +827,10 @@ func (p *Parser) primary() Expr {
 		return NewLiteral(p.previous().Literal)
 	}
 
+	if p.match(INTERPOLATION) {
+		return p.interpolation()
+	}
+
 	if p.match(SUPER) {
 		keyword := p.previous()
 		p.consume(DOT, "Expect '.' after 'super'.")
@@ -392,12 +848,51 @@ func (p *Parser) primary() Expr {
 		return NewVariable(p.previous())
 	}
 
+	if p.match(FUN) {
+		return p.lambda()
+	}
+
+	if p.check(LEFT_PAREN) && p.isArrow() {
+		return p.arrow()
+	}
+
 	if p.match(LEFT_PAREN) {
 		expr := p.expression()
 		p.consume(RIGHT_PAREN, "Expect ')' after expression.")
 		return NewGrouping(expr)
 	}
 
+	if p.match(LEFT_BRACKET) {
+		bracket := p.previous()
+		elements := make([]Expr, 0)
+		if !p.check(RIGHT_BRACKET) {
+			elements = append(elements, p.expression())
+			for p.match(COMMA) {
+				elements = append(elements, p.expression())
+			}
+		}
+		p.consume(RIGHT_BRACKET, "Expect ']' after list elements.")
+		return NewList(bracket, elements)
+	}
+
+	if p.match(LEFT_BRACE) {
+		brace := p.previous()
+		keys := make([]Expr, 0)
+		values := make([]Expr, 0)
+		if !p.check(RIGHT_BRACE) {
+			for {
+				keys = append(keys, p.expression())
+				p.consume(COLON, "Expect ':' after map key.")
+				values = append(values, p.expression())
+				if !p.match(COMMA) {
+					break
+				}
+			}
+		}
+		p.consume(RIGHT_BRACE, "Expect '}' after map entries.")
+		return NewMap(brace, keys, values)
+	}
+
 	panic(NewParseError(p.peek(), "Expect expression."))
 }
 
@@ -0,0 +1,36 @@
+package lox_test
+
+import (
+	"strings"
+	"testing"
+
+	"lox/lox"
+)
+
+func TestRunPrompt(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine lox.Engine) {
+		var out, errs strings.Builder
+		input := strings.Join([]string{
+			`var count = 1;`,
+			`print missing;`,
+			`print count + 1;`,
+			`print (;`,
+			`count = count + 10;`,
+			`print count;`,
+		}, "\n") + "\n"
+		l := newLox(engine, &out, lox.WithStdin(strings.NewReader(input)), lox.WithStderr(&errs))
+
+		if err := l.RunPrompt(); err != nil {
+			t.Fatal(err)
+		}
+		// Globals survive the runtime and parse errors in between.
+		if got, want := out.String(), "> > > 2\n> > > 11\n> "; got != want {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		for _, want := range []string{"Undefined variable 'missing'.", "Expect expression."} {
+			if !strings.Contains(errs.String(), want) {
+				t.Errorf("got errors %q, want them to contain %q", errs.String(), want)
+			}
+		}
+	})
+}
@@ -1,10 +1,13 @@
 package lox
 
 type Resolver struct {
+	lox             *Lox
 	interpreter     *Interpreter
 	scopes          []map[string]bool
 	currentFunction int
 	currentClass    int
+	loopDepth       int
+	generator       bool
 }
 
 const (
@@ -20,14 +23,14 @@ const (
 	CLS_SUBCLASS
 )
 
-func NewResolver(interpreter *Interpreter) *Resolver {
-	return &Resolver{interpreter, make([]map[string]bool, 0), FN_NONE, CLS_NONE}
+func NewResolver(lox *Lox, interpreter *Interpreter) *Resolver {
+	return &Resolver{lox, interpreter, make([]map[string]bool, 0), FN_NONE, CLS_NONE, 0, false}
 }
 
 func (r *Resolver) ResolveStatements(statements []Stmt) {
 	defer func() {
-		if r := recover(); r != nil {
-			LoxInstance.Report(r.(error))
+		if err := recover(); err != nil {
+			r.lox.Report(err.(error))
 		}
 	}()
 
@@ -37,9 +40,7 @@ func (r *Resolver) ResolveStatements(statements []Stmt) {
 }
 
 func (r *Resolver) VisitBlockStmt(stmt *Block) any {
-	r.beginScope()
-	r.ResolveStatements(stmt.Statements)
-	r.endScope()
+	r.resolveBlock(stmt.Statements)
 	return nil
 }
 
@@ -78,6 +79,9 @@ func (r *Resolver) VisitClassStmt(stmt *Class) any {
 		}
 		r.resolveFunction(method, declaration)
 	}
+	for _, method := range stmt.ClassMethods {
+		r.resolveFunction(method, FN_METHOD)
+	}
 
 	r.endScope()
 
@@ -86,6 +90,12 @@ func (r *Resolver) VisitClassStmt(stmt *Class) any {
 	}
 
 	r.currentClass = enclosingClass
+
+	for _, field := range stmt.Fields {
+		if field.Initializer != nil {
+			r.resolveExpr(field.Initializer)
+		}
+	}
 	return nil
 }
 
@@ -111,6 +121,23 @@ func (r *Resolver) VisitIfStmt(stmt *If) any {
 	return nil
 }
 
+func (r *Resolver) VisitMatchStmt(stmt *Match) any {
+	r.resolveExpr(stmt.Value)
+	for k, patterns := range stmt.Patterns {
+		for _, pattern := range patterns {
+			r.resolveExpr(pattern)
+		}
+		if stmt.Guards[k] != nil {
+			r.resolveExpr(stmt.Guards[k])
+		}
+		r.resolveStatement(stmt.Bodies[k])
+	}
+	if stmt.Otherwise != nil {
+		r.resolveStatement(stmt.Otherwise)
+	}
+	return nil
+}
+
 func (r *Resolver) VisitPrintStmt(stmt *Print) any {
 	r.resolveExpr(stmt.Expression)
 	return nil
@@ -128,11 +155,49 @@ func (r *Resolver) VisitReturnStmt(stmt *Return) any {
 				stmt.Keyword, "Can't return a value from an initializer.",
 			))
 		}
+		if r.generator {
+			panic(NewResolveError(
+				stmt.Keyword, "Can't return a value from a generator.",
+			))
+		}
+		r.resolveExpr(stmt.Value)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitYieldStmt(stmt *Yield) any {
+	if r.currentFunction == FN_NONE {
+		panic(NewResolveError(stmt.Keyword, "Can't yield outside a function."))
+	}
+	if r.currentFunction == FN_INITIALIZER {
+		panic(NewResolveError(stmt.Keyword, "Can't yield from an initializer."))
+	}
+	if stmt.Value != nil {
 		r.resolveExpr(stmt.Value)
 	}
 	return nil
 }
 
+func (r *Resolver) VisitThrowStmt(stmt *Throw) any {
+	r.resolveExpr(stmt.Value)
+	return nil
+}
+
+func (r *Resolver) VisitTryStmt(stmt *Try) any {
+	r.resolveBlock(stmt.Body)
+	if stmt.Name != nil {
+		r.beginScope()
+		r.declare(stmt.Name)
+		r.define(stmt.Name)
+		r.ResolveStatements(stmt.Handler)
+		r.endScope()
+	}
+	if stmt.Finally != nil {
+		r.resolveBlock(stmt.Finally)
+	}
+	return nil
+}
+
 func (r *Resolver) VisitVarStmt(stmt *Var) any {
 	r.declare(stmt.Name)
 	if stmt.Initializer != nil {
@@ -144,7 +209,58 @@ func (r *Resolver) VisitVarStmt(stmt *Var) any {
 
 func (r *Resolver) VisitWhileStmt(stmt *While) any {
 	r.resolveExpr(stmt.Condition)
+	r.loopDepth++
 	r.resolveStatement(stmt.Body)
+	r.loopDepth--
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitForInStmt(stmt *ForIn) any {
+	r.resolveExpr(stmt.Iterable)
+	r.beginScope()
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.loopDepth++
+	r.resolveStatement(stmt.Body)
+	r.loopDepth--
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitExportStmt(stmt *Export) any {
+	if len(r.scopes) > 0 {
+		panic(NewResolveError(stmt.Keyword, "Can only export top-level declarations."))
+	}
+	r.resolveStatement(stmt.Declaration)
+	return nil
+}
+
+func (r *Resolver) VisitImportStmt(stmt *Import) any {
+	if stmt.Alias != nil {
+		r.declare(stmt.Alias)
+		r.define(stmt.Alias)
+	}
+	for _, name := range stmt.Names {
+		r.declare(name)
+		r.define(name)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitBreakStmt(stmt *Break) any {
+	if r.loopDepth == 0 {
+		panic(NewResolveError(stmt.Keyword, "Can't use 'break' outside of a loop."))
+	}
+	return nil
+}
+
+func (r *Resolver) VisitContinueStmt(stmt *Continue) any {
+	if r.loopDepth == 0 {
+		panic(NewResolveError(stmt.Keyword, "Can't use 'continue' outside of a loop."))
+	}
 	return nil
 }
 
@@ -170,6 +286,19 @@ func (r *Resolver) VisitCallExpr(expr *Call) any {
 	return nil
 }
 
+func (r *Resolver) VisitCompoundExpr(expr *Compound) any {
+	r.resolveExpr(expr.Target)
+	r.resolveExpr(expr.Value)
+	return nil
+}
+
+func (r *Resolver) VisitConditionalExpr(expr *Conditional) any {
+	r.resolveExpr(expr.Condition)
+	r.resolveExpr(expr.ThenBranch)
+	r.resolveExpr(expr.ElseBranch)
+	return nil
+}
+
 func (r *Resolver) VisitGetExpr(expr *Get) any {
 	r.resolveExpr(expr.Object)
 	return nil
@@ -180,6 +309,24 @@ func (r *Resolver) VisitGroupingExpr(expr *Grouping) any {
 	return nil
 }
 
+func (r *Resolver) VisitIndexExpr(expr *Index) any {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+
+func (r *Resolver) VisitLambdaExpr(expr *Lambda) any {
+	r.resolveFunction(expr.Function, FN_FUNCTION)
+	return nil
+}
+
+func (r *Resolver) VisitListExpr(expr *List) any {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
 func (r *Resolver) VisitLiteralExpr(expr *Literal) any {
 	return nil
 }
@@ -190,12 +337,32 @@ func (r *Resolver) VisitLogicalExpr(expr *Logical) any {
 	return nil
 }
 
+func (r *Resolver) VisitMapExpr(expr *Map) any {
+	for k := range expr.Keys {
+		r.resolveExpr(expr.Keys[k])
+		r.resolveExpr(expr.Values[k])
+	}
+	return nil
+}
+
 func (r *Resolver) VisitSetExpr(expr *Set) any {
 	r.resolveExpr(expr.Value)
 	r.resolveExpr(expr.Object)
 	return nil
 }
 
+func (r *Resolver) VisitSetIndexExpr(expr *SetIndex) any {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	r.resolveExpr(expr.Value)
+	return nil
+}
+
+func (r *Resolver) VisitStringifyExpr(expr *Stringify) any {
+	r.resolveExpr(expr.Expression)
+	return nil
+}
+
 func (r *Resolver) VisitSuperExpr(expr *Super) any {
 	if r.currentClass == CLS_NONE {
 		panic(NewResolveError(
@@ -229,7 +396,7 @@ func (r *Resolver) VisitVariableExpr(expr *Variable) any {
 		val, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]
 		if ok && !val {
 			panic(NewResolveError(
-				expr.Name, "Can't read local variable in it's own initializer.",
+				expr.Name, "Can't read local variable in its own initializer.",
 			))
 		}
 	}
@@ -246,17 +413,36 @@ func (r *Resolver) resolveExpr(expr Expr) {
 	expr.Accept(r)
 }
 
+func (r *Resolver) resolveBlock(statements []Stmt) {
+	r.beginScope()
+	r.ResolveStatements(statements)
+	r.endScope()
+}
+
 func (r *Resolver) resolveFunction(function *Function, type_ int) {
 	enclosingFunction := r.currentFunction
+	enclosingLoopDepth := r.loopDepth
+	enclosingGenerator := r.generator
 	r.currentFunction = type_
+	r.loopDepth = 0
+	r.generator = function.Generator
 	r.beginScope()
-	for _, param := range function.Params {
+	for k, param := range function.Params {
+		if function.Defaults[k] != nil {
+			r.resolveExpr(function.Defaults[k])
+		}
 		r.declare(param)
 		r.define(param)
 	}
+	if function.Rest != nil {
+		r.declare(function.Rest)
+		r.define(function.Rest)
+	}
 	r.ResolveStatements(function.Body)
 	r.endScope()
 	r.currentFunction = enclosingFunction
+	r.loopDepth = enclosingLoopDepth
+	r.generator = enclosingGenerator
 }
 
 func (r *Resolver) resolveLocal(expr Expr, name *Token) {
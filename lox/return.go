@@ -7,3 +7,12 @@ type ReturnValue struct {
 func NewReturnValue(value any) *ReturnValue {
 	return &ReturnValue{value}
 }
+
+// LoopSignal unwinds the body of a loop on break or continue.
+type LoopSignal struct {
+	Keyword *Token
+}
+
+func NewLoopSignal(keyword *Token) *LoopSignal {
+	return &LoopSignal{keyword}
+}
@@ -2,42 +2,62 @@ package lox
 
 import (
 	"fmt"
-	"os"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type Scanner struct {
-	source  string
-	tokens  []*Token
-	start   int
-	current int
-	line    int
+	lox       *Lox
+	source    string
+	tokens    []*Token
+	start     int
+	current   int
+	line      int
+	lineStart int
+	// interpolations holds the brace depth of every string interpolation
+	// being scanned, innermost last.
+	interpolations []int
 }
 
 var keywords = map[string]int{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
-}
-
-func NewScanner(source string) *Scanner {
+	"and":      AND,
+	"break":    BREAK,
+	"case":     CASE,
+	"catch":    CATCH,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"default":  DEFAULT,
+	"else":     ELSE,
+	"export":   EXPORT,
+	"false":    FALSE,
+	"finally":  FINALLY,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"import":   IMPORT,
+	"match":    MATCH,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"throw":    THROW,
+	"true":     TRUE,
+	"try":      TRY,
+	"var":      VAR,
+	"while":    WHILE,
+	"yield":    YIELD,
+}
+
+func NewScanner(lox *Lox, source string) *Scanner {
 	return &Scanner{
+		lox,
 		source,
 		make([]*Token, 0),
-		0, 0, 1,
+		0, 0, 1, 0,
+		nil,
 	}
 }
 
@@ -46,6 +66,9 @@ func (s *Scanner) ScanTokens() []*Token {
 		s.start = s.current
 		s.scanToken()
 	}
+	if len(s.interpolations) > 0 {
+		s.error(s.current, "Unterminated string.")
+	}
 	s.tokens = append(s.tokens, &Token{EOF, "", nil, s.line})
 	return s.tokens
 }
@@ -58,25 +81,73 @@ func (s *Scanner) scanToken() {
 	case ')':
 		s.addToken(RIGHT_PAREN, nil)
 	case '{':
+		if depth := len(s.interpolations); depth > 0 {
+			s.interpolations[depth-1]++
+		}
 		s.addToken(LEFT_BRACE, nil)
 	case '}':
+		if depth := len(s.interpolations); depth > 0 {
+			if s.interpolations[depth-1] == 0 {
+				s.interpolations = s.interpolations[:depth-1]
+				s.string()
+				return
+			}
+			s.interpolations[depth-1]--
+		}
 		s.addToken(RIGHT_BRACE, nil)
+	case '[':
+		s.addToken(LEFT_BRACKET, nil)
+	case ']':
+		s.addToken(RIGHT_BRACKET, nil)
 	case ',':
 		s.addToken(COMMA, nil)
 	case '.':
-		s.addToken(DOT, nil)
+		if s.peek() == '.' && s.peekNext() == '.' {
+			s.current += 2
+			s.addToken(ELLIPSIS, nil)
+		} else {
+			s.addToken(DOT, nil)
+		}
 	case '-':
-		s.addToken(MINUS, nil)
+		if s.match('-') {
+			s.addToken(MINUS_MINUS, nil)
+		} else {
+			s.addToken(s.ifMatch('=', MINUS_EQUAL, MINUS), nil)
+		}
 	case '+':
-		s.addToken(PLUS, nil)
+		if s.match('+') {
+			s.addToken(PLUS_PLUS, nil)
+		} else {
+			s.addToken(s.ifMatch('=', PLUS_EQUAL, PLUS), nil)
+		}
 	case ';':
 		s.addToken(SEMICOLON, nil)
+	case ':':
+		s.addToken(COLON, nil)
+	case '%':
+		s.addToken(PERCENT, nil)
+	case '?':
+		s.addToken(QUESTION, nil)
 	case '*':
-		s.addToken(STAR, nil)
+		if s.match('*') {
+			s.addToken(STAR_STAR, nil)
+		} else {
+			s.addToken(s.ifMatch('=', STAR_EQUAL, STAR), nil)
+		}
+	case '~':
+		if s.match('/') {
+			s.addToken(TILDE_SLASH, nil)
+		} else {
+			s.error(s.start, "Unexpected character.")
+		}
 	case '!':
 		s.addToken(s.ifMatch('=', BANG_EQUAL, BANG), nil)
 	case '=':
-		s.addToken(s.ifMatch('=', EQUAL_EQUAL, EQUAL), nil)
+		if s.match('>') {
+			s.addToken(ARROW, nil)
+		} else {
+			s.addToken(s.ifMatch('=', EQUAL_EQUAL, EQUAL), nil)
+		}
 	case '<':
 		s.addToken(s.ifMatch('=', LESS_EQUAL, LESS), nil)
 	case '>':
@@ -87,20 +158,22 @@ func (s *Scanner) scanToken() {
 				s.advance()
 			}
 		} else {
-			s.addToken(SLASH, nil)
+			s.addToken(s.ifMatch('=', SLASH_EQUAL, SLASH), nil)
 		}
 	case ' ', '\r', '\t':
 	case '\n':
-		s.line++
+		s.newline()
 	case '"':
 		s.string()
+	case '`':
+		s.rawString()
 	default:
 		if s.isDigit(rune(c)) {
 			s.number()
 		} else if s.isAlpha(rune(c)) {
 			s.identifier()
 		} else {
-			LoxInstance.Report(NewScanError(s.line, "Unexpected character"))
+			s.error(s.start, "Unexpected character.")
 		}
 	}
 }
@@ -130,26 +203,107 @@ func (s *Scanner) number() {
 	}
 	value, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
 	if err != nil {
-		fmt.Printf("Failed to parse a floating point number: %s\n", err)
-		os.Exit(64)
+		s.error(s.start, fmt.Sprintf("Failed to parse a floating point number: %s", err))
+		return
 	}
 	s.addToken(NUMBER, value)
 }
 
+// string scans a string literal after its opening quote, or the rest of
+// one after an interpolated expression. Every '${' ends the current part
+// with an INTERPOLATION token; the part after the last expression is an
+// ordinary STRING.
 func (s *Scanner) string() {
+	var value strings.Builder
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		c := s.advance()
+		switch {
+		case c == '\\':
+			s.escape(&value)
+		case c == '$' && s.peek() == '{':
+			s.advance()
+			s.addToken(INTERPOLATION, value.String())
+			s.interpolations = append(s.interpolations, 0)
+			return
+		default:
+			if c == '\n' {
+				s.newline()
+			}
+			value.WriteByte(c)
 		}
-		s.advance()
 	}
 	if s.isAtEnd() {
-		LoxInstance.Report(NewScanError(s.line, "Unterminated string"))
+		s.error(s.current, "Unterminated string.")
+		s.interpolations = nil
 		return
 	}
 	s.advance()
-	value := s.source[s.start+1 : s.current-1]
-	s.addToken(STRING, value)
+	s.addToken(STRING, value.String())
+}
+
+// rawString scans a backtick string, which may span lines and has no
+// escapes or interpolation.
+func (s *Scanner) rawString() {
+	for s.peek() != '`' && !s.isAtEnd() {
+		if s.advance() == '\n' {
+			s.newline()
+		}
+	}
+	if s.isAtEnd() {
+		s.error(s.current, "Unterminated string.")
+		s.interpolations = nil
+		return
+	}
+	s.advance()
+	s.addToken(STRING, s.source[s.start+1:s.current-1])
+}
+
+// escape decodes the escape sequence after a backslash into value.
+func (s *Scanner) escape(value *strings.Builder) {
+	start := s.current - 1
+	if s.isAtEnd() {
+		return
+	}
+
+	c, size := utf8.DecodeRuneInString(s.source[s.current:])
+	s.current += size
+	switch c {
+	case 'n':
+		value.WriteByte('\n')
+	case 't':
+		value.WriteByte('\t')
+	case 'r':
+		value.WriteByte('\r')
+	case '0':
+		value.WriteByte(0)
+	case '"', '\\', '$':
+		value.WriteRune(c)
+	case 'u':
+		s.unicodeEscape(value, start)
+	default:
+		s.error(start, fmt.Sprintf("Invalid escape sequence '\\%c'.", c))
+	}
+}
+
+// unicodeEscape decodes '\uXXXX' or '\u{X...}' after the 'u'.
+func (s *Scanner) unicodeEscape(value *strings.Builder, start int) {
+	braced := s.match('{')
+	digits := s.current
+	for s.isHexDigit(s.peek()) && (braced || s.current-digits < 4) {
+		s.advance()
+	}
+	hex := s.source[digits:s.current]
+	if (braced && !s.match('}')) || (!braced && len(hex) != 4) || len(hex) == 0 {
+		s.error(start, "Invalid unicode escape sequence.")
+		return
+	}
+
+	code, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		s.error(start, "Invalid unicode escape sequence.")
+		return
+	}
+	value.WriteRune(rune(code))
 }
 
 func (s *Scanner) ifMatch(expected rune, consequent TokenType, alternate TokenType) TokenType {
@@ -200,6 +354,21 @@ func (s *Scanner) isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
+func (s *Scanner) isHexDigit(c rune) bool {
+	return s.isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func (s *Scanner) newline() {
+	s.line++
+	s.lineStart = s.current
+}
+
+// error reports a scan error at the byte offset in the current line.
+func (s *Scanner) error(offset int, message string) {
+	column := utf8.RuneCountInString(s.source[s.lineStart:offset]) + 1
+	s.lox.Report(NewScanError(s.line, column, message))
+}
+
 func (s *Scanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
@@ -0,0 +1,119 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"time"
+)
+
+func (i *Interpreter) defineStdlib() {
+	i.DefineNative("Error", 1, newError)
+	// range takes no keyword arguments, since its first parameter is the end
+	// when it is the only one.
+	i.builtins.Define("range", NewNativeFuncRange("range", 1, 3, newRange))
+	i.DefineRestricted("clock", CapTime, 0, clock)
+	i.DefineRestricted("readFile", CapFSRead, 1, readFile)
+	i.DefineRestricted("writeFile", CapFSWrite, 2, writeFile)
+	i.DefineRestricted("getenv", CapEnv, 1, getenv)
+	i.DefineRestricted("exec", CapExec, Variadic, execCommand)
+}
+
+func newError(i *Interpreter, args []any) (any, error) {
+	return NewLoxError(i.stringify(args[0], i.callSite())), nil
+}
+
+// newRange creates the range from start to end, or from zero to the only
+// argument.
+func newRange(i *Interpreter, args []any) (any, error) {
+	numbers := []float64{0, 0, 1}
+	for k, arg := range args {
+		if arg == nil {
+			continue
+		}
+		number, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("Range bounds and step must be numbers.")
+		}
+		if math.IsNaN(number) {
+			return nil, fmt.Errorf("Range bounds and step can't be NaN.")
+		}
+		numbers[k] = number
+	}
+	if len(args) == 1 {
+		numbers[0], numbers[1] = 0, numbers[0]
+	}
+	// An infinite start would repeat itself forever.
+	if math.IsInf(numbers[0], 0) {
+		return nil, fmt.Errorf("Range start must be finite.")
+	}
+	if numbers[2] == 0 {
+		return nil, fmt.Errorf("Range step can't be zero.")
+	}
+	return NewLoxRange(numbers[0], numbers[1], numbers[2]), nil
+}
+
+func clock(i *Interpreter, args []any) (any, error) {
+	return float64(time.Now().UnixMilli()) / 1000.0, nil
+}
+
+func readFile(i *Interpreter, args []any) (any, error) {
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("Path must be a string.")
+	}
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return string(bytes), nil
+}
+
+func writeFile(i *Interpreter, args []any) (any, error) {
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("Path must be a string.")
+	}
+	return nil, os.WriteFile(path, []byte(i.stringify(args[1], i.callSite())), 0644)
+}
+
+func getenv(i *Interpreter, args []any) (any, error) {
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("Variable name must be a string.")
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// execCommand runs a program and returns its standard output. The command
+// is stopped when the script's context is cancelled.
+func execCommand(i *Interpreter, args []any) (any, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Expected a command.")
+	}
+
+	words := make([]string, len(args))
+	for k, arg := range args {
+		word, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("Command arguments must be strings.")
+		}
+		words[k] = word
+	}
+
+	ctx := i.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	output, err := exec.CommandContext(ctx, words[0], words[1:]...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return string(output), nil
+}
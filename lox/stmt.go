@@ -2,14 +2,23 @@ package lox
 
 type StmtVisitor interface {
 	VisitBlockStmt(stmt *Block) any
+	VisitBreakStmt(stmt *Break) any
 	VisitClassStmt(stmt *Class) any
+	VisitContinueStmt(stmt *Continue) any
+	VisitExportStmt(stmt *Export) any
 	VisitExpressionStmt(stmt *Expression) any
+	VisitForInStmt(stmt *ForIn) any
 	VisitFunctionStmt(stmt *Function) any
 	VisitIfStmt(stmt *If) any
+	VisitImportStmt(stmt *Import) any
+	VisitMatchStmt(stmt *Match) any
 	VisitPrintStmt(stmt *Print) any
 	VisitReturnStmt(stmt *Return) any
+	VisitThrowStmt(stmt *Throw) any
+	VisitTryStmt(stmt *Try) any
 	VisitVarStmt(stmt *Var) any
 	VisitWhileStmt(stmt *While) any
+	VisitYieldStmt(stmt *Yield) any
 }
 
 type Stmt interface {
@@ -28,20 +37,59 @@ func (b *Block) Accept(sv StmtVisitor) any {
 	return sv.VisitBlockStmt(b)
 }
 
+type Break struct {
+	Keyword *Token
+}
+
+func NewBreak(keyword *Token, ) Stmt {
+	return &Break{ keyword,  }
+}
+
+func (b *Break) Accept(sv StmtVisitor) any {
+	return sv.VisitBreakStmt(b)
+}
+
 type Class struct {
 	Name *Token
 	Superclass *Variable
 	Methods []*Function
+	ClassMethods []*Function
+	Fields []*Var
 }
 
-func NewClass(name *Token, superclass *Variable, methods []*Function, ) Stmt {
-	return &Class{ name, superclass, methods,  }
+func NewClass(name *Token, superclass *Variable, methods []*Function, classMethods []*Function, fields []*Var, ) Stmt {
+	return &Class{ name, superclass, methods, classMethods, fields,  }
 }
 
 func (c *Class) Accept(sv StmtVisitor) any {
 	return sv.VisitClassStmt(c)
 }
 
+type Continue struct {
+	Keyword *Token
+}
+
+func NewContinue(keyword *Token, ) Stmt {
+	return &Continue{ keyword,  }
+}
+
+func (c *Continue) Accept(sv StmtVisitor) any {
+	return sv.VisitContinueStmt(c)
+}
+
+type Export struct {
+	Keyword *Token
+	Declaration Stmt
+}
+
+func NewExport(keyword *Token, declaration Stmt, ) Stmt {
+	return &Export{ keyword, declaration,  }
+}
+
+func (e *Export) Accept(sv StmtVisitor) any {
+	return sv.VisitExportStmt(e)
+}
+
 type Expression struct {
 	Expression Expr
 }
@@ -54,14 +102,32 @@ func (e *Expression) Accept(sv StmtVisitor) any {
 	return sv.VisitExpressionStmt(e)
 }
 
+type ForIn struct {
+	Keyword *Token
+	Name *Token
+	Iterable Expr
+	Body Stmt
+}
+
+func NewForIn(keyword *Token, name *Token, iterable Expr, body Stmt, ) Stmt {
+	return &ForIn{ keyword, name, iterable, body,  }
+}
+
+func (f *ForIn) Accept(sv StmtVisitor) any {
+	return sv.VisitForInStmt(f)
+}
+
 type Function struct {
 	Name *Token
 	Params []*Token
+	Defaults []Expr
+	Rest *Token
 	Body []Stmt
+	Generator bool
 }
 
-func NewFunction(name *Token, params []*Token, body []Stmt, ) Stmt {
-	return &Function{ name, params, body,  }
+func NewFunction(name *Token, params []*Token, defaults []Expr, rest *Token, body []Stmt, generator bool, ) Stmt {
+	return &Function{ name, params, defaults, rest, body, generator,  }
 }
 
 func (f *Function) Accept(sv StmtVisitor) any {
@@ -69,25 +135,59 @@ func (f *Function) Accept(sv StmtVisitor) any {
 }
 
 type If struct {
+	Keyword *Token
 	Condition Expr
 	ThenBranch Stmt
 	ElseBranch Stmt
 }
 
-func NewIf(condition Expr, thenBranch Stmt, elseBranch Stmt, ) Stmt {
-	return &If{ condition, thenBranch, elseBranch,  }
+func NewIf(keyword *Token, condition Expr, thenBranch Stmt, elseBranch Stmt, ) Stmt {
+	return &If{ keyword, condition, thenBranch, elseBranch,  }
 }
 
 func (i *If) Accept(sv StmtVisitor) any {
 	return sv.VisitIfStmt(i)
 }
 
+type Import struct {
+	Keyword *Token
+	Path *Token
+	Alias *Token
+	Names []*Token
+}
+
+func NewImport(keyword *Token, path *Token, alias *Token, names []*Token, ) Stmt {
+	return &Import{ keyword, path, alias, names,  }
+}
+
+func (i *Import) Accept(sv StmtVisitor) any {
+	return sv.VisitImportStmt(i)
+}
+
+type Match struct {
+	Keyword *Token
+	Value Expr
+	Patterns [][]Expr
+	Guards []Expr
+	Bodies []Stmt
+	Otherwise Stmt
+}
+
+func NewMatch(keyword *Token, value Expr, patterns [][]Expr, guards []Expr, bodies []Stmt, otherwise Stmt, ) Stmt {
+	return &Match{ keyword, value, patterns, guards, bodies, otherwise,  }
+}
+
+func (m *Match) Accept(sv StmtVisitor) any {
+	return sv.VisitMatchStmt(m)
+}
+
 type Print struct {
+	Keyword *Token
 	Expression Expr
 }
 
-func NewPrint(expression Expr, ) Stmt {
-	return &Print{ expression,  }
+func NewPrint(keyword *Token, expression Expr, ) Stmt {
+	return &Print{ keyword, expression,  }
 }
 
 func (p *Print) Accept(sv StmtVisitor) any {
@@ -107,6 +207,35 @@ func (r *Return) Accept(sv StmtVisitor) any {
 	return sv.VisitReturnStmt(r)
 }
 
+type Throw struct {
+	Keyword *Token
+	Value Expr
+}
+
+func NewThrow(keyword *Token, value Expr, ) Stmt {
+	return &Throw{ keyword, value,  }
+}
+
+func (t *Throw) Accept(sv StmtVisitor) any {
+	return sv.VisitThrowStmt(t)
+}
+
+type Try struct {
+	Keyword *Token
+	Body []Stmt
+	Name *Token
+	Handler []Stmt
+	Finally []Stmt
+}
+
+func NewTry(keyword *Token, body []Stmt, name *Token, handler []Stmt, finally []Stmt, ) Stmt {
+	return &Try{ keyword, body, name, handler, finally,  }
+}
+
+func (t *Try) Accept(sv StmtVisitor) any {
+	return sv.VisitTryStmt(t)
+}
+
 type Var struct {
 	Name *Token
 	Initializer Expr
@@ -121,15 +250,30 @@ func (v *Var) Accept(sv StmtVisitor) any {
 }
 
 type While struct {
+	Keyword *Token
 	Condition Expr
 	Body Stmt
+	Increment Expr
 }
 
-func NewWhile(condition Expr, body Stmt, ) Stmt {
-	return &While{ condition, body,  }
+func NewWhile(keyword *Token, condition Expr, body Stmt, increment Expr, ) Stmt {
+	return &While{ keyword, condition, body, increment,  }
 }
 
 func (w *While) Accept(sv StmtVisitor) any {
 	return sv.VisitWhileStmt(w)
 }
 
+type Yield struct {
+	Keyword *Token
+	Value Expr
+}
+
+func NewYield(keyword *Token, value Expr, ) Stmt {
+	return &Yield{ keyword, value,  }
+}
+
+func (y *Yield) Accept(sv StmtVisitor) any {
+	return sv.VisitYieldStmt(y)
+}
+
@@ -8,10 +8,15 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
+	COLON
 	COMMA
 	DOT
 	MINUS
+	PERCENT
 	PLUS
+	QUESTION
 	SEMICOLON
 	SLASH
 	STAR
@@ -19,35 +24,58 @@ const (
 	// One or two character tokens.
 	BANG
 	BANG_EQUAL
+	ELLIPSIS
 	EQUAL
 	EQUAL_EQUAL
+	ARROW
 	GREATER
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	MINUS_EQUAL
+	MINUS_MINUS
+	PLUS_EQUAL
+	PLUS_PLUS
+	SLASH_EQUAL
+	STAR_EQUAL
+	STAR_STAR
+	TILDE_SLASH
 
 	// Literals.
 	IDENTIFIER
 	STRING
+	INTERPOLATION
 	NUMBER
 
 	// Keywords.
 	AND
+	BREAK
+	CASE
+	CATCH
 	CLASS
+	CONTINUE
+	DEFAULT
 	ELSE
+	EXPORT
 	FALSE
+	FINALLY
 	FUN
 	FOR
 	IF
+	IMPORT
+	MATCH
 	NIL
 	OR
 	PRINT
 	RETURN
 	SUPER
 	THIS
+	THROW
 	TRUE
+	TRY
 	VAR
 	WHILE
+	YIELD
 
 	EOF
 )
@@ -0,0 +1,559 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+)
+
+// vmFrame is an activation of a closure on the VM. Slots of the frame
+// start at base, which holds the callee or the receiver.
+type vmFrame struct {
+	closure *Closure
+	ip      int
+	base    int
+	traced  bool
+}
+
+// handler is the catch target of a try statement being executed. Catching
+// an error unwinds the VM and the interpreter's call stack to their state
+// when the try statement was entered.
+type handler struct {
+	frame int
+	stack int
+	ip    int
+	calls int
+	depth int
+}
+
+// VM executes bytecode produced by the Compiler. It shares the runtime of
+// the Interpreter: globals, natives, classes, instances and the semantics
+// of operators all come from there.
+type VM struct {
+	interpreter  *Interpreter
+	stack        []any
+	frames       []*vmFrame
+	handlers     []handler
+	openUpvalues *Upvalue
+	// yielded is set when the generator running on the VM yields.
+	yielded bool
+}
+
+func NewVM(interpreter *Interpreter) *VM {
+	return &VM{interpreter: interpreter}
+}
+
+// vmState is the part of the VM an error unwinds through.
+type vmState struct {
+	stack    int
+	frames   int
+	handlers int
+}
+
+func (vm *VM) save() vmState {
+	return vmState{len(vm.stack), len(vm.frames), len(vm.handlers)}
+}
+
+func (vm *VM) restore(state vmState) {
+	vm.closeUpvalues(state.stack)
+	vm.stack = vm.stack[:state.stack]
+	vm.frames = vm.frames[:state.frames]
+	vm.handlers = vm.handlers[:state.handlers]
+}
+
+func (vm *VM) Interpret(script *Prototype) error {
+	i := vm.interpreter
+	err := i.protect(func() {
+		vm.script(NewClosure(script, i.globals))
+	})
+	if err != nil {
+		i.lox.Report(err)
+	}
+	return err
+}
+
+// script runs the top-level code of a file. Unlike a call, it does not
+// count towards the call depth.
+func (vm *VM) script(closure *Closure) {
+	vm.push(closure)
+	vm.frames = append(vm.frames, &vmFrame{closure: closure, base: len(vm.stack) - 1})
+	vm.run(len(vm.frames) - 1)
+}
+
+// call runs closure to completion and returns its result. receiver is the
+// instance or class a method was bound to, or nil for plain functions.
+func (vm *VM) call(closure *Closure, receiver any, arguments []any) any {
+	var callee any = closure
+	if receiver != nil {
+		callee = receiver
+	}
+	if closure.Function.IsGenerator {
+		return vm.generator(closure, callee, arguments)
+	}
+
+	exit := len(vm.frames)
+	vm.push(callee)
+	vm.stack = append(vm.stack, arguments...)
+	vm.pushFrame(closure, len(arguments), false)
+	return vm.run(exit)
+}
+
+func (vm *VM) pushFrame(closure *Closure, argc int, traced bool) {
+	vm.interpreter.checkCallDepth()
+	vm.interpreter.depth++
+
+	argc = vm.arguments(closure.Function, argc)
+	vm.frames = append(vm.frames, &vmFrame{
+		closure: closure,
+		base:    len(vm.stack) - argc - 1,
+		traced:  traced,
+	})
+}
+
+// arguments lays out the argc arguments on top of the stack as the slots
+// of function's parameters and returns their number. Left out arguments
+// are marked missing for the function's prologue to fill in, and the
+// arguments past the parameters are collected for a rest parameter.
+func (vm *VM) arguments(function *Prototype, argc int) int {
+	for ; argc < function.Arity; argc++ {
+		vm.push(missing)
+	}
+	if function.Rest {
+		rest := make([]any, argc-function.Arity)
+		copy(rest, vm.stack[len(vm.stack)-len(rest):])
+		vm.stack = vm.stack[:len(vm.stack)-len(rest)]
+		vm.push(NewLoxList(rest))
+		argc = function.Arity + 1
+	}
+	return argc
+}
+
+// generator returns a generator for a call of closure. The body runs on a
+// VM of its own, which holds its frame between steps.
+func (vm *VM) generator(closure *Closure, callee any, arguments []any) *LoxGenerator {
+	body := NewVM(vm.interpreter)
+	body.push(callee)
+	body.stack = append(body.stack, arguments...)
+	body.arguments(closure.Function, len(arguments))
+	body.frames = []*vmFrame{{closure: closure}}
+	return newGenerator(vm.interpreter, closure, &vmGenerator{vm: body})
+}
+
+// callValue calls the value below the argc arguments on top of the stack.
+// Closures get a new frame; any other callable runs to completion and its
+// result replaces the callee and arguments.
+func (vm *VM) callValue(argc int, paren *Token) {
+	i := vm.interpreter
+	slot := len(vm.stack) - argc - 1
+
+	switch callee := vm.stack[slot].(type) {
+	case *Closure:
+		if callee.Function.IsGenerator {
+			break
+		}
+		i.checkArity(callee, argc, paren)
+		i.pushFrame(callee, paren)
+		vm.pushFrame(callee, argc, true)
+		return
+	case *BoundMethod:
+		if callee.Method.Function.IsGenerator {
+			break
+		}
+		i.checkArity(callee, argc, paren)
+		vm.stack[slot] = callee.Receiver
+		i.pushFrame(callee, paren)
+		vm.pushFrame(callee.Method, argc, true)
+		return
+	case *LoxClass:
+		if initializer, ok := callee.FindMethod("init").(*Closure); ok {
+			i.checkArity(callee, argc, paren)
+			vm.stack[slot] = NewInstance(callee)
+			i.pushFrame(callee, paren)
+			vm.pushFrame(initializer, argc, true)
+			return
+		}
+	}
+
+	arguments := make([]any, argc)
+	copy(arguments, vm.stack[slot+1:])
+	result := i.callValue(vm.stack[slot], arguments, paren)
+	vm.stack = vm.stack[:slot]
+	vm.push(result)
+}
+
+// run executes until the frame at index exit returns, resuming at the
+// handler of a try statement when an error is caught.
+func (vm *VM) run(exit int) any {
+	for {
+		if result, returned := vm.resume(exit); returned {
+			return result
+		}
+	}
+}
+
+func (vm *VM) resume(exit int) (result any, returned bool) {
+	defer func() {
+		if r := recover(); r != nil && !vm.catch(r, exit) {
+			panic(r)
+		}
+	}()
+	return vm.execute(exit), true
+}
+
+// catch unwinds to the innermost handler if it belongs to a frame at or
+// above exit, and leaves the error on the stack for it.
+func (vm *VM) catch(r any, exit int) bool {
+	i := vm.interpreter
+	err, ok := r.(*RuntimeError)
+	if !ok || err.Fatal || len(vm.handlers) == 0 {
+		return false
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	if h.frame < exit {
+		return false
+	}
+
+	if err.Frames == nil {
+		err.Frames = i.traceback(err.Token)
+	}
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.closeUpvalues(h.stack)
+	vm.stack = vm.stack[:h.stack]
+	vm.frames = vm.frames[:h.frame+1]
+	vm.frames[h.frame].ip = h.ip
+	i.frames = i.frames[:h.calls]
+	i.depth = h.depth
+	vm.push(err)
+	return true
+}
+
+func (vm *VM) execute(exit int) any {
+	i := vm.interpreter
+	frame := vm.frames[len(vm.frames)-1]
+	chunk := frame.closure.Function.Chunk
+	code := chunk.Code
+
+	readByte := func() int {
+		frame.ip++
+		return int(code[frame.ip-1])
+	}
+	readShort := func() int {
+		frame.ip += 2
+		return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+	}
+	token := func() *Token {
+		return chunk.Tokens[frame.ip-1]
+	}
+	reload := func() {
+		frame = vm.frames[len(vm.frames)-1]
+		chunk = frame.closure.Function.Chunk
+		code = chunk.Code
+	}
+
+	for {
+		i.steps++
+		if i.steps%contextCheckInterval == 0 ||
+			(i.lox.maxSteps > 0 && i.steps > i.lox.maxSteps) {
+			i.location = chunk.Tokens[frame.ip]
+			i.checkLimits()
+		}
+
+		switch op := OpCode(readByte()); op {
+		case OP_CONSTANT:
+			vm.push(chunk.Constants[readShort()])
+		case OP_NIL:
+			vm.push(nil)
+		case OP_TRUE:
+			vm.push(true)
+		case OP_FALSE:
+			vm.push(false)
+		case OP_POP:
+			vm.pop()
+		case OP_DUP:
+			count := readByte()
+			vm.stack = append(vm.stack, vm.stack[len(vm.stack)-count:]...)
+		case OP_BURY:
+			depth := readByte()
+			top := vm.pop()
+			k := len(vm.stack) - depth
+			vm.stack = append(vm.stack, nil)
+			copy(vm.stack[k+1:], vm.stack[k:])
+			vm.stack[k] = top
+		case OP_GET_LOCAL:
+			vm.push(vm.stack[frame.base+readByte()])
+		case OP_SET_LOCAL:
+			vm.stack[frame.base+readByte()] = vm.peek(0)
+		case OP_GET_GLOBAL:
+			name := chunk.Constants[readShort()].(string)
+			value, ok := frame.closure.Globals.Values[name]
+			if !ok {
+				value = frame.closure.Globals.Get(token())
+			}
+			vm.push(value)
+		case OP_DEFINE_GLOBAL:
+			name := chunk.Constants[readShort()].(string)
+			frame.closure.Globals.Define(name, vm.pop())
+		case OP_SET_GLOBAL:
+			readShort()
+			frame.closure.Globals.Assign(token(), vm.peek(0))
+		case OP_GET_UPVALUE:
+			vm.push(frame.closure.Upvalues[readByte()].Get())
+		case OP_SET_UPVALUE:
+			frame.closure.Upvalues[readByte()].Set(vm.peek(0))
+		case OP_GET_PROPERTY:
+			readShort()
+			object := vm.pop()
+			vm.push(i.getProperty(object, token()))
+		case OP_SET_PROPERTY:
+			readShort()
+			value := vm.pop()
+			object := vm.pop()
+			i.setProperty(object, token(), value)
+			vm.push(value)
+		case OP_GET_SUPER:
+			readShort()
+			superclass := vm.pop().(*LoxClass)
+			object := vm.pop()
+			vm.push(i.superMethod(superclass, object, token()))
+		case OP_GET_INDEX:
+			index := vm.pop()
+			object := vm.pop()
+			vm.push(i.getIndex(object, index, token()))
+		case OP_SET_INDEX:
+			value := vm.pop()
+			index := vm.pop()
+			object := vm.pop()
+			i.setIndex(object, index, value, token())
+			vm.push(value)
+		case OP_LIST:
+			count := readShort()
+			elements := make([]any, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(NewLoxList(elements))
+		case OP_MAP:
+			count := 2 * readShort()
+			entries := vm.stack[len(vm.stack)-count:]
+			m := i.newMap(entries, token())
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(m)
+		case OP_EQUAL:
+			b, a := vm.pop(), vm.pop()
+			vm.push(i.isEqual(a, b, token()))
+		case OP_MATCH:
+			pattern, value := vm.pop(), vm.pop()
+			vm.push(i.matches(value, pattern, token()))
+		case OP_NOT_EQUAL:
+			b, a := vm.pop(), vm.pop()
+			vm.push(!i.isEqual(a, b, token()))
+		case OP_GREATER, OP_GREATER_EQUAL, OP_LESS, OP_LESS_EQUAL,
+			OP_ADD, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE, OP_MODULO,
+			OP_INT_DIVIDE, OP_POWER:
+			b, a := vm.pop(), vm.pop()
+			vm.push(vm.arithmetic(op, a, b, token))
+		case OP_NOT:
+			vm.push(!i.isTruthy(vm.pop()))
+		case OP_NEGATE:
+			value := vm.pop()
+			if number, ok := value.(float64); ok {
+				vm.push(-number)
+			} else {
+				vm.push(i.unaryOp(token(), value))
+			}
+		case OP_STRINGIFY:
+			vm.push(i.stringify(vm.pop(), token()))
+		case OP_PRINT:
+			fmt.Fprintln(i.lox.stdout, i.stringify(vm.pop(), token()))
+		case OP_JUMP:
+			offset := readShort()
+			frame.ip += offset
+		case OP_JUMP_IF_FALSE:
+			offset := readShort()
+			if !i.isTruthy(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case OP_JUMP_IF_GIVEN:
+			offset := readShort()
+			if vm.pop() != missing {
+				frame.ip += offset
+			}
+		case OP_ITERATOR:
+			vm.push(i.iterate(vm.pop(), token()))
+		case OP_FOR_ITER:
+			offset := readShort()
+			if value, ok := vm.peek(0).(Iterator).Next(i); ok {
+				vm.push(value)
+			} else {
+				frame.ip += offset
+			}
+		case OP_LOOP:
+			offset := readShort()
+			frame.ip -= offset
+		case OP_CALL:
+			argc := readByte()
+			vm.callValue(argc, token())
+			reload()
+		case OP_CALL_KEYWORDS:
+			names := chunk.Constants[readShort()].(*keywordArguments).names
+			argc := readByte()
+			slot := len(vm.stack) - argc - 1
+			arguments := i.bindArguments(vm.stack[slot], vm.stack[slot+1:], names, token())
+			vm.stack = append(vm.stack[:slot+1], arguments...)
+			vm.callValue(len(arguments), token())
+			reload()
+		case OP_CLOSURE:
+			function := chunk.Constants[readShort()].(*Prototype)
+			closure := NewClosure(function, frame.closure.Globals)
+			for k := range closure.Upvalues {
+				isLocal := readByte()
+				index := readByte()
+				if isLocal == 1 {
+					closure.Upvalues[k] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.Upvalues[k] = frame.closure.Upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OP_RETURN:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:frame.base]
+			if frame.closure.Function.Name != "" {
+				i.depth--
+			}
+			if frame.traced {
+				i.popFrame()
+			}
+			if len(vm.frames) == exit {
+				return result
+			}
+			vm.push(result)
+			reload()
+		case OP_YIELD:
+			vm.yielded = true
+			return vm.pop()
+		case OP_THROW:
+			panic(i.thrown(token(), vm.pop()))
+		case OP_TRY:
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{
+				frame: len(vm.frames) - 1,
+				stack: len(vm.stack),
+				ip:    frame.ip + offset,
+				calls: len(i.frames),
+				depth: i.depth,
+			})
+		case OP_POP_HANDLER:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case OP_CATCH:
+			vm.push(caught(vm.pop().(*RuntimeError)))
+		case OP_RETHROW:
+			panic(vm.pop().(*RuntimeError))
+		case OP_IMPORT:
+			path := chunk.Constants[readShort()].(string)
+			vm.push(i.importModule(token(), path, frame.closure.Globals))
+		case OP_CLASS:
+			name := chunk.Constants[readShort()].(string)
+			vm.push(NewLoxClass(name, nil, make(map[string]Method)))
+		case OP_CLASS_METHOD:
+			name := chunk.Constants[readShort()].(string)
+			method := vm.pop().(*Closure)
+			class := vm.peek(0).(*LoxClass)
+			method.Class = class
+			class.ClassMethods[name] = method
+		case OP_INHERIT:
+			superclass, ok := vm.peek(1).(*LoxClass)
+			if !ok {
+				panic(NewRuntimeError(token(), "Superclass must be a class."))
+			}
+			vm.pop().(*LoxClass).Superclass = superclass
+		case OP_METHOD:
+			name := chunk.Constants[readShort()].(string)
+			method := vm.pop().(*Closure)
+			class := vm.peek(0).(*LoxClass)
+			method.Class = class
+			class.Methods[name] = method
+		default:
+			panic(fmt.Sprintf("unknown opcode %v", op))
+		}
+	}
+}
+
+// arithmetic applies a binary operator, taking a fast path when both
+// operands are numbers.
+func (vm *VM) arithmetic(op OpCode, a, b any, token func() *Token) any {
+	x, xok := a.(float64)
+	y, yok := b.(float64)
+	if xok && yok {
+		switch op {
+		case OP_GREATER:
+			return x > y
+		case OP_GREATER_EQUAL:
+			return x >= y
+		case OP_LESS:
+			return x < y
+		case OP_LESS_EQUAL:
+			return x <= y
+		case OP_ADD:
+			return x + y
+		case OP_SUBTRACT:
+			return x - y
+		case OP_MULTIPLY:
+			return x * y
+		case OP_DIVIDE, OP_MODULO, OP_INT_DIVIDE:
+			return vm.interpreter.divide(token(), x, y)
+		case OP_POWER:
+			return math.Pow(x, y)
+		}
+	}
+	return vm.interpreter.binaryOp(token(), a, b)
+}
+
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var previous *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		previous = upvalue
+		upvalue = upvalue.next
+	}
+
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	created := &Upvalue{vm: vm, slot: slot, open: true, next: upvalue}
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves the variables at or above slot off the stack and
+// into the upvalues that captured them.
+func (vm *VM) closeUpvalues(slot int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= slot {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+func (vm *VM) push(value any) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() any {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek(distance int) any {
+	return vm.stack[len(vm.stack)-1-distance]
+}
@@ -1,20 +1,87 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"lox/lox"
 )
 
+// capabilities collects the values of repeated --allow flags.
+type capabilities []lox.Capability
+
+func (c *capabilities) String() string {
+	names := make([]string, len(*c))
+	for k, capability := range *c {
+		names[k] = string(capability)
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *capabilities) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		capability, err := lox.ParseCapability(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		*c = append(*c, capability)
+	}
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: lox [--allow=capability,...] [--engine=tree|vm] [script | test <dir>]")
+	flag.PrintDefaults()
+}
+
 func main() {
-	lox := lox.NewLox()
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: lox [script]")
+	var allowed capabilities
+	flag.Var(&allowed, "allow", fmt.Sprintf(
+		"grant capabilities to natives (%s)", capabilitiesList()))
+	engineName := flag.String("engine", string(lox.EngineInterpreter),
+		"execution engine (tree or vm)")
+	flag.Usage = usage
+	flag.Parse()
+
+	engine, err := lox.ParseEngine(*engineName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		usage()
 		os.Exit(64)
-	} else if len(os.Args) == 2 {
-		lox.RunFile(os.Args[1])
+	}
+	options := []lox.Option{lox.WithCapabilities(allowed...), lox.WithEngine(engine)}
+
+	if flag.NArg() == 2 && flag.Arg(0) == "test" {
+		_, failed, err := lox.RunConformanceTests(flag.Arg(1), os.Stdout, options...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(64)
+		} else if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	l := lox.New(options...)
+	if flag.NArg() > 1 {
+		usage()
+		os.Exit(64)
+	} else if flag.NArg() == 1 {
+		err := l.RunFile(flag.Arg(0))
+		os.Exit(lox.ExitCode(err))
 	} else {
-		lox.RunPrompt()
+		if err := l.RunPrompt(); err != nil {
+			os.Exit(64)
+		}
+	}
+}
+
+func capabilitiesList() string {
+	names := make([]string, len(lox.Capabilities))
+	for k, capability := range lox.Capabilities {
+		names[k] = string(capability)
 	}
+	return strings.Join(names, ", ")
 }
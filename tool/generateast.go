@@ -17,12 +17,21 @@ func main() {
 	defineAst(outputDir, "Expr", []string{
 		"Assign		: name *Token, value Expr",
 		"Binary		: left Expr, operator *Token, right Expr",
-		"Call		: callee Expr, paren *Token, arguments []Expr",
+		"Call		: callee Expr, paren *Token, arguments []Expr, names []*Token",
+		"Compound	: target Expr, operator *Token, value Expr, postfix bool",
+		"Conditional	: condition Expr, question *Token, thenBranch Expr," +
+			" elseBranch Expr",
 		"Get		: object Expr, name *Token",
 		"Grouping	: expression Expr",
+		"Index		: object Expr, bracket *Token, index Expr",
+		"Lambda		: keyword *Token, function *Function",
+		"List		: bracket *Token, elements []Expr",
 		"Literal	: value any",
 		"Logical	: left Expr, operator *Token, right Expr",
+		"Map		: brace *Token, keys []Expr, values []Expr",
 		"Set		: object Expr, name *Token, value Expr",
+		"Stringify	: token *Token, expression Expr",
+		"SetIndex	: object Expr, bracket *Token, index Expr, value Expr",
 		"Super		: keyword *Token, method *Token",
 		"This		: keyword *Token",
 		"Unary		: operator *Token, right Expr",
@@ -30,15 +39,29 @@ func main() {
 	})
 	defineAst(outputDir, "Stmt", []string{
 		"Block		: statements []Stmt",
+		"Break		: keyword *Token",
 		"Class		: name *Token, superclass *Variable," +
-			" methods []*Function",
+			" methods []*Function, classMethods []*Function, fields []*Var",
+		"Continue	: keyword *Token",
+		"Export		: keyword *Token, declaration Stmt",
 		"Expression	: expression Expr",
-		"Function	: name *Token, params []*Token, body []Stmt",
-		"If		: condition Expr, thenBranch Stmt, elseBranch Stmt",
-		"Print		: expression Expr",
+		"ForIn		: keyword *Token, name *Token, iterable Expr, body Stmt",
+		"Function	: name *Token, params []*Token, defaults []Expr, rest *Token," +
+			" body []Stmt, generator bool",
+		"If		: keyword *Token, condition Expr, thenBranch Stmt, elseBranch Stmt",
+		"Import		: keyword *Token, path *Token, alias *Token," +
+			" names []*Token",
+		"Match		: keyword *Token, value Expr, patterns [][]Expr," +
+			" guards []Expr, bodies []Stmt, otherwise Stmt",
+		"Print		: keyword *Token, expression Expr",
 		"Return		: keyword *Token, value Expr",
+		"Throw		: keyword *Token, value Expr",
+		"Try		: keyword *Token, body []Stmt, name *Token," +
+			" handler []Stmt, finally []Stmt",
 		"Var		: name *Token, initializer Expr",
-		"While		: condition Expr, body Stmt",
+		"While		: keyword *Token, condition Expr, body Stmt," +
+			" increment Expr",
+		"Yield		: keyword *Token, value Expr",
 	})
 }
 